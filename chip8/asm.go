@@ -0,0 +1,401 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Disassemble returns the mnemonic of an instruction, in the syntax of Cowgod's Chip-8 technical reference
+// parsed by Assemble. The values which are not instructions are data words, "DW".
+func Disassemble(val uint16) string {
+	x, y, n := val>>8&0xF, val>>4&0xF, val&0xF
+	kk, nnn := val&0xFF, val&0xFFF
+	switch val >> 12 {
+	case 0x0:
+		switch val {
+		case 0x00E0:
+			return "CLS"
+		case 0x00EE:
+			return "RET"
+		}
+		return fmt.Sprintf("SYS 0x%03X", nnn)
+	case 0x1:
+		return fmt.Sprintf("JP 0x%03X", nnn)
+	case 0x2:
+		return fmt.Sprintf("CALL 0x%03X", nnn)
+	case 0x3:
+		return fmt.Sprintf("SE V%X, 0x%02X", x, kk)
+	case 0x4:
+		return fmt.Sprintf("SNE V%X, 0x%02X", x, kk)
+	case 0x5:
+		if n == 0 {
+			return fmt.Sprintf("SE V%X, V%X", x, y)
+		}
+	case 0x6:
+		return fmt.Sprintf("LD V%X, 0x%02X", x, kk)
+	case 0x7:
+		return fmt.Sprintf("ADD V%X, 0x%02X", x, kk)
+	case 0x8:
+		if op, ok := aluMnemonics[n]; ok {
+			return fmt.Sprintf("%s V%X, V%X", op, x, y)
+		}
+	case 0x9:
+		if n == 0 {
+			return fmt.Sprintf("SNE V%X, V%X", x, y)
+		}
+	case 0xA:
+		return fmt.Sprintf("LD I, 0x%03X", nnn)
+	case 0xB:
+		return fmt.Sprintf("JP V0, 0x%03X", nnn)
+	case 0xC:
+		return fmt.Sprintf("RND V%X, 0x%02X", x, kk)
+	case 0xD:
+		return fmt.Sprintf("DRW V%X, V%X, %d", x, y, n)
+	case 0xE:
+		switch kk {
+		case 0x9E:
+			return fmt.Sprintf("SKP V%X", x)
+		case 0xA1:
+			return fmt.Sprintf("SKNP V%X", x)
+		}
+	case 0xF:
+		if format, ok := fxMnemonics[kk]; ok {
+			return fmt.Sprintf(format, x)
+		}
+	}
+	return fmt.Sprintf("DW 0x%04X", val)
+}
+
+// aluMnemonics are the instructions 8xyn by n.
+var aluMnemonics = map[uint16]string{
+	0x0: "LD", 0x1: "OR", 0x2: "AND", 0x3: "XOR", 0x4: "ADD", 0x5: "SUB", 0x6: "SHR", 0x7: "SUBN", 0xE: "SHL",
+}
+
+// fxMnemonics are the formats of the instructions Fxkk by kk.
+var fxMnemonics = map[uint16]string{
+	0x07: "LD V%X, DT", 0x0A: "LD V%X, K", 0x15: "LD DT, V%X", 0x18: "LD ST, V%X", 0x1E: "ADD I, V%X",
+	0x29: "LD F, V%X", 0x33: "LD B, V%X", 0x55: "LD [I], V%X", 0x65: "LD V%X, [I]",
+}
+
+// WriteDisassembly writes the disassembly of a ROM, one instruction per line with its address and value in a comment.
+// The disassembly assembles back into the ROM.
+func WriteDisassembly(w io.Writer, data []byte) error {
+	for i := 0; i < len(data); i += InstructionSize {
+		addr := ProgramLocation + i
+		var err error
+		if i+1 < len(data) {
+			val := uint16(data[i])<<8 | uint16(data[i+1])
+			_, err = fmt.Fprintf(w, "%-20s ; %04X: %04X\n", Disassemble(val), addr, val)
+		} else {
+			_, err = fmt.Fprintf(w, "%-20s ; %04X: %02X\n", fmt.Sprintf("DB 0x%02X", data[i]), addr, data[i])
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to write disassembly")
+		}
+	}
+	return nil
+}
+
+// field is where an operand is encoded in an instruction.
+type field int
+
+const (
+	fieldNone field = iota // the operand is part of the mnemonic
+	fieldX                 // register in the second nibble
+	fieldY                 // register in the third nibble
+	fieldN                 // nibble
+	fieldKK                // byte
+	fieldNNN               // address
+	fieldV0                // V0, the only register allowed
+)
+
+// encoding is the opcode of an instruction and where its operands go.
+type encoding struct {
+	opcode uint16
+	fields []field
+}
+
+// encodings are the instructions by mnemonic and kinds of operands:
+// V for a register, N for a number or a label, and the special operands I, [I], DT, ST, K, F and B.
+var encodings = map[string]encoding{
+	"CLS":       {0x00E0, nil},
+	"RET":       {0x00EE, nil},
+	"SYS N":     {0x0000, []field{fieldNNN}},
+	"JP N":      {0x1000, []field{fieldNNN}},
+	"JP V,N":    {0xB000, []field{fieldV0, fieldNNN}},
+	"CALL N":    {0x2000, []field{fieldNNN}},
+	"SE V,N":    {0x3000, []field{fieldX, fieldKK}},
+	"SE V,V":    {0x5000, []field{fieldX, fieldY}},
+	"SNE V,N":   {0x4000, []field{fieldX, fieldKK}},
+	"SNE V,V":   {0x9000, []field{fieldX, fieldY}},
+	"LD V,N":    {0x6000, []field{fieldX, fieldKK}},
+	"LD V,V":    {0x8000, []field{fieldX, fieldY}},
+	"LD I,N":    {0xA000, []field{fieldNone, fieldNNN}},
+	"LD V,DT":   {0xF007, []field{fieldX, fieldNone}},
+	"LD V,K":    {0xF00A, []field{fieldX, fieldNone}},
+	"LD DT,V":   {0xF015, []field{fieldNone, fieldX}},
+	"LD ST,V":   {0xF018, []field{fieldNone, fieldX}},
+	"LD F,V":    {0xF029, []field{fieldNone, fieldX}},
+	"LD B,V":    {0xF033, []field{fieldNone, fieldX}},
+	"LD [I],V":  {0xF055, []field{fieldNone, fieldX}},
+	"LD V,[I]":  {0xF065, []field{fieldX, fieldNone}},
+	"ADD V,N":   {0x7000, []field{fieldX, fieldKK}},
+	"ADD V,V":   {0x8004, []field{fieldX, fieldY}},
+	"ADD I,V":   {0xF01E, []field{fieldNone, fieldX}},
+	"OR V,V":    {0x8001, []field{fieldX, fieldY}},
+	"AND V,V":   {0x8002, []field{fieldX, fieldY}},
+	"XOR V,V":   {0x8003, []field{fieldX, fieldY}},
+	"SUB V,V":   {0x8005, []field{fieldX, fieldY}},
+	"SHR V":     {0x8006, []field{fieldX}},
+	"SHR V,V":   {0x8006, []field{fieldX, fieldY}},
+	"SUBN V,V":  {0x8007, []field{fieldX, fieldY}},
+	"SHL V":     {0x800E, []field{fieldX}},
+	"SHL V,V":   {0x800E, []field{fieldX, fieldY}},
+	"RND V,N":   {0xC000, []field{fieldX, fieldKK}},
+	"DRW V,V,N": {0xD000, []field{fieldX, fieldY, fieldN}},
+	"SKP V":     {0xE09E, []field{fieldX}},
+	"SKNP V":    {0xE0A1, []field{fieldX}},
+}
+
+// operand is an operand of a line of assembly.
+type operand struct {
+	kind  string // as in encodings
+	text  string
+	value int
+}
+
+// statement is a line of assembly with an instruction or data.
+type statement struct {
+	line     int
+	addr     int
+	mnemonic string
+	operands []operand
+}
+
+// Assemble assembles a program written with the mnemonics of Cowgod's Chip-8 technical reference,
+// one instruction per line, into a ROM loaded at ProgramLocation.
+// Lines may start with labels such as "loop:", which instructions use as addresses, and comments start with ";".
+// The numbers are decimal, hexadecimal with "0x" or "#", or binary with "0b".
+// DB and DW insert bytes and words separated by commas.
+func Assemble(source io.Reader) ([]byte, error) {
+	labels := make(map[string]int)
+	var statements []statement
+	addr := ProgramLocation
+
+	// first pass, the addresses of the labels
+	scanner := bufio.NewScanner(source)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.IndexByte(text, ';'); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		for {
+			i := strings.IndexByte(text, ':')
+			if i < 0 {
+				break
+			}
+			label := strings.TrimSpace(text[:i])
+			if !isLabel(label) {
+				return nil, errors.Errorf("line %d: invalid label %q", line, label)
+			}
+			if _, ok := labels[label]; ok {
+				return nil, errors.Errorf("line %d: label %q defined twice", line, label)
+			}
+			labels[label] = addr
+			text = strings.TrimSpace(text[i+1:])
+		}
+		if text == "" {
+			continue
+		}
+
+		s := statement{line: line, addr: addr}
+		fields := strings.SplitN(text, " ", 2)
+		s.mnemonic = strings.ToUpper(fields[0])
+		if len(fields) > 1 {
+			for _, op := range strings.Split(fields[1], ",") {
+				s.operands = append(s.operands, parseOperand(strings.TrimSpace(op)))
+			}
+		}
+		switch s.mnemonic {
+		case "DB":
+			addr += len(s.operands)
+		case "DW":
+			addr += 2 * len(s.operands)
+		default:
+			addr += InstructionSize
+		}
+		statements = append(statements, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read assembly")
+	}
+	if addr > RamSize {
+		return nil, errors.Errorf("program of %d bytes too large", addr-ProgramLocation)
+	}
+
+	// second pass, the instructions
+	var rom []byte
+	for _, s := range statements {
+		for i := range s.operands {
+			op := &s.operands[i]
+			if op.kind != "label" {
+				continue
+			}
+			value, ok := labels[op.text]
+			if !ok {
+				return nil, errors.Errorf("line %d: unknown label %q", s.line, op.text)
+			}
+			op.kind, op.value = "N", value
+		}
+		code, err := s.assemble()
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", s.line)
+		}
+		rom = append(rom, code...)
+	}
+	return rom, nil
+}
+
+// assemble returns the bytes of a statement.
+func (s statement) assemble() ([]byte, error) {
+	var code []byte
+	switch s.mnemonic {
+	case "DB", "DW":
+		for _, op := range s.operands {
+			if op.kind != "N" {
+				return nil, errors.Errorf("invalid data %q", op.text)
+			}
+			if s.mnemonic == "DB" {
+				if op.value < -0x80 || op.value > 0xFF {
+					return nil, errors.Errorf("byte %s out of range", op.text)
+				}
+				code = append(code, byte(op.value))
+			} else {
+				if op.value < -0x8000 || op.value > 0xFFFF {
+					return nil, errors.Errorf("word %s out of range", op.text)
+				}
+				code = append(code, byte(op.value>>8), byte(op.value))
+			}
+		}
+		return code, nil
+	}
+
+	kinds := make([]string, len(s.operands))
+	for i, op := range s.operands {
+		kinds[i] = op.kind
+	}
+	key := strings.TrimSpace(s.mnemonic + " " + strings.Join(kinds, ","))
+	enc, ok := encodings[key]
+	if !ok {
+		return nil, errors.Errorf("invalid instruction %q", strings.TrimSpace(s.mnemonic+" "+s.text()))
+	}
+	val := enc.opcode
+	for i, f := range enc.fields {
+		op := s.operands[i]
+		limit, shift := 0, uint(0)
+		switch f {
+		case fieldX:
+			limit, shift = 0xF, 8
+		case fieldY:
+			limit, shift = 0xF, 4
+		case fieldN:
+			limit = 0xF
+		case fieldKK:
+			limit = 0xFF
+		case fieldNNN:
+			limit = 0xFFF
+		case fieldV0:
+			if op.value != 0 {
+				return nil, errors.Errorf("register %s instead of V0", op.text)
+			}
+			continue
+		default:
+			continue
+		}
+		value := op.value
+		if f == fieldKK && value < 0 && value >= -0x80 {
+			value &= 0xFF
+		}
+		if value < 0 || value > limit {
+			return nil, errors.Errorf("%s out of range", op.text)
+		}
+		val |= uint16(value) << shift
+	}
+	return []byte{byte(val >> 8), byte(val)}, nil
+}
+
+// text returns the operands as written.
+func (s statement) text() string {
+	texts := make([]string, len(s.operands))
+	for i, op := range s.operands {
+		texts[i] = op.text
+	}
+	return strings.Join(texts, ", ")
+}
+
+// parseOperand returns the kind of an operand, and its value for registers and numbers.
+func parseOperand(text string) operand {
+	op := operand{text: text}
+	upper := strings.ToUpper(text)
+	switch upper {
+	case "I", "[I]", "DT", "ST", "K", "F", "B":
+		op.kind = upper
+		return op
+	}
+	if len(upper) == 2 && upper[0] == 'V' {
+		if v, err := strconv.ParseUint(upper[1:], 16, 4); err == nil {
+			op.kind, op.value = "V", int(v)
+			return op
+		}
+	}
+	if value, ok := parseNumber(text); ok {
+		op.kind, op.value = "N", value
+		return op
+	}
+	op.kind = "label"
+	return op
+}
+
+// parseNumber parses a decimal, hexadecimal or binary number, maybe negative.
+func parseNumber(text string) (int, bool) {
+	sign := 1
+	if strings.HasPrefix(text, "-") {
+		sign, text = -1, text[1:]
+	}
+	base := 10
+	lower := strings.ToLower(text)
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		base, text = 16, text[2:]
+	case strings.HasPrefix(lower, "#"):
+		base, text = 16, text[1:]
+	case strings.HasPrefix(lower, "0b"):
+		base, text = 2, text[2:]
+	}
+	value, err := strconv.ParseUint(text, base, 16)
+	if err != nil {
+		return 0, false
+	}
+	return sign * int(value), true
+}
+
+// isLabel tells if a name can be a label: a letter or an underscore followed by letters, digits and underscores,
+// which is not a register.
+func isLabel(name string) bool {
+	if name == "" || parseOperand(name).kind != "label" {
+		return false
+	}
+	for i, c := range name {
+		letter := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
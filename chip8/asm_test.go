@@ -0,0 +1,60 @@
+package chip8_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssemble(t *testing.T) {
+	rom, err := chip8.Assemble(strings.NewReader(`
+	; draws the sprite of 0 until a key is pressed
+start:	CLS
+	ld v0, 0
+	LD F, V0
+loop:	DRW V1, V2, 5
+	ADD V1, #08
+	LD VA, K
+	SE VA, -1
+	JP loop
+	JP V0, start
+sprite:	DB 0b11110000, 0x90
+	DW sprite
+`))
+	require.NoError(t, err)
+	require.Equal(t, []byte{
+		0x00, 0xE0, 0x60, 0x00, 0xF0, 0x29, 0xD1, 0x25, 0x71, 0x08,
+		0xFA, 0x0A, 0x3A, 0xFF, 0x12, 0x06, 0xB2, 0x00, 0xF0, 0x90, 0x02, 0x12,
+	}, rom)
+
+	for source, message := range map[string]string{
+		"JP nowhere":     "line 1: unknown label \"nowhere\"",
+		"LD V1, 256":     "line 1: 256 out of range",
+		"JP V1, 0x200":   "line 1: register V1 instead of V0",
+		"a: CLS\na: RET": "line 2: label \"a\" defined twice",
+		"LD K, V1":       "line 1: invalid instruction \"LD K, V1\"",
+	} {
+		_, err := chip8.Assemble(strings.NewReader(source))
+		require.EqualError(t, err, message, source)
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	// every value disassembles into an instruction or data assembling back into it
+	for val := 0; val <= 0xFFFF; val++ {
+		rom, err := chip8.Assemble(strings.NewReader(chip8.Disassemble(uint16(val))))
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(val >> 8), byte(val)}, rom)
+	}
+
+	var source bytes.Buffer
+	require.NoError(t, chip8.WriteDisassembly(&source, []byte{0x6A, 0x02, 0xD1, 0x25, 0x12}))
+	require.Equal(t, "LD VA, 0x02          ; 0200: 6A02\nDRW V1, V2, 5        ; 0202: D125\nDB 0x12              ; 0204: 12\n", source.String())
+
+	require.Equal(t, chip8.PlatformCHIP8, chip8.DetectPlatform([]byte{0x00, 0xE0, 0x00, 0xC0}))
+	require.Equal(t, chip8.PlatformSuperCHIP, chip8.DetectPlatform([]byte{0x00, 0xFF, 0x00, 0xE0}))
+	require.Equal(t, chip8.PlatformXOCHIP, chip8.DetectPlatform([]byte{0x00, 0xFF, 0xF0, 0x00, 0x12, 0x34}))
+}
@@ -0,0 +1,203 @@
+package chip8
+
+import (
+	"encoding/binary"
+	"io"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	SampleRate      = 44100
+	SamplesPerTick  = SampleRate / TimerFrequency
+	DefaultPitch    = 440
+	DefaultVolume   = 0.25
+	toneRampSeconds = 0.002 // length of the attack and release of the tone
+)
+
+// AudioSink receives the state of the buzzer on every tick of the timers.
+// The buzzer is on as long as the sound timer is active.
+type AudioSink interface {
+	Tick(buzzer bool) error
+	Close() error
+}
+
+// NullSink discards the sound.
+type NullSink struct{}
+
+// Tick does nothing.
+func (NullSink) Tick(buzzer bool) error {
+	return nil
+}
+
+// Close does nothing.
+func (NullSink) Close() error {
+	return nil
+}
+
+// Tone is a square wave generator.
+// The amplitude is ramped up and down when the buzzer is turned on and off so the speaker doesn't click.
+type Tone struct {
+	Pitch  float64 // frequency in Hz
+	Volume float64 // between 0 and 1
+	phase  float64
+	gain   float64
+}
+
+func NewTone(pitch, volume float64) *Tone {
+	return &Tone{Pitch: pitch, Volume: volume}
+}
+
+// Generate fills samples with the next part of the wave.
+func (t *Tone) Generate(samples []int16, buzzer bool) {
+	target := 0.0
+	if buzzer {
+		target = 1
+	}
+	step := 1 / (toneRampSeconds * SampleRate)
+	for i := range samples {
+		switch {
+		case t.gain < target:
+			t.gain += step
+			if t.gain > target {
+				t.gain = target
+			}
+		case t.gain > target:
+			t.gain -= step
+			if t.gain < target {
+				t.gain = target
+			}
+		}
+		if t.gain == 0 {
+			// restart the wave at the beginning of its period on the next beep
+			t.phase = 0
+			samples[i] = 0
+			continue
+		}
+
+		amplitude := t.gain * t.Volume * 0x7FFF
+		if t.phase < 0.5 {
+			samples[i] = int16(amplitude)
+		} else {
+			samples[i] = int16(-amplitude)
+		}
+		t.phase += t.Pitch / SampleRate
+		for t.phase >= 1 {
+			t.phase--
+		}
+	}
+}
+
+// ToneSink streams the tone as signed 16 bits little endian mono PCM.
+type ToneSink struct {
+	tone    *Tone
+	out     io.WriteCloser
+	samples [SamplesPerTick]int16
+}
+
+func NewToneSink(out io.WriteCloser, tone *Tone) *ToneSink {
+	return &ToneSink{tone: tone, out: out}
+}
+
+// Tick writes the samples of one tick.
+func (s *ToneSink) Tick(buzzer bool) error {
+	s.tone.Generate(s.samples[:], buzzer)
+	if err := binary.Write(s.out, binary.LittleEndian, s.samples[:]); err != nil {
+		return errors.Wrap(err, "failed to play sound")
+	}
+	return nil
+}
+
+// Close closes the output stream.
+func (s *ToneSink) Close() error {
+	return s.out.Close()
+}
+
+// NewSpeakerSink plays the tone on the speakers through aplay.
+func NewSpeakerSink(tone *Tone) (*ToneSink, error) {
+	cmd := exec.Command("aplay", "-q", "-t", "raw", "-f", "S16_LE", "-c", "1", "-r", "44100")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open speaker")
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "failed to open speaker")
+	}
+	return NewToneSink(&process{stdin, cmd}, tone), nil
+}
+
+// process closes the input of a command and waits for it to exit.
+type process struct {
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+func (p *process) Close() error {
+	if err := p.WriteCloser.Close(); err != nil {
+		return err
+	}
+	return p.cmd.Wait()
+}
+
+// WAVSink records the tone into a WAV file.
+type WAVSink struct {
+	tone    *Tone
+	out     io.WriteSeeker
+	samples [SamplesPerTick]int16
+	count   uint32
+}
+
+// NewWAVSink writes the header of the file. The sizes are filled in when the sink is closed.
+func NewWAVSink(out io.WriteSeeker, tone *Tone) (*WAVSink, error) {
+	s := &WAVSink{tone: tone, out: out}
+	if err := s.writeHeader(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *WAVSink) writeHeader() error {
+	size := s.count * 2
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'}, 36 + size, [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, uint32(16),
+		uint16(1),              // PCM
+		uint16(1),              // mono
+		uint32(SampleRate),     // sample rate
+		uint32(SampleRate * 2), // byte rate
+		uint16(2),              // block align
+		uint16(16),             // bits per sample
+		[4]byte{'d', 'a', 't', 'a'}, size,
+	}
+	for _, field := range header {
+		if err := binary.Write(s.out, binary.LittleEndian, field); err != nil {
+			return errors.Wrap(err, "failed to write wav header")
+		}
+	}
+	return nil
+}
+
+// Tick records the samples of one tick.
+func (s *WAVSink) Tick(buzzer bool) error {
+	s.tone.Generate(s.samples[:], buzzer)
+	if err := binary.Write(s.out, binary.LittleEndian, s.samples[:]); err != nil {
+		return errors.Wrap(err, "failed to write wav data")
+	}
+	s.count += SamplesPerTick
+	return nil
+}
+
+// Close updates the header with the length of the recording and closes the file.
+func (s *WAVSink) Close() error {
+	if _, err := s.out.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to write wav header")
+	}
+	if err := s.writeHeader(); err != nil {
+		return err
+	}
+	if closer, ok := s.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
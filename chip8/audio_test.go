@@ -0,0 +1,56 @@
+package chip8_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAVSink(t *testing.T) {
+	file, err := ioutil.TempFile("", "chip8-*.wav")
+	require.Nil(t, err)
+	defer os.Remove(file.Name())
+
+	sink, err := chip8.NewWAVSink(file, chip8.NewTone(chip8.DefaultPitch, chip8.DefaultVolume))
+	require.Nil(t, err)
+	for _, buzzer := range []bool{false, true, true, false, false} {
+		require.Nil(t, sink.Tick(buzzer))
+	}
+	require.Nil(t, sink.Close())
+
+	data, err := ioutil.ReadFile(file.Name())
+	require.Nil(t, err)
+	require.Equal(t, "RIFF", string(data[0:4]))
+	require.Equal(t, "WAVE", string(data[8:12]))
+	require.Equal(t, uint32(5*chip8.SamplesPerTick*2), binary.LittleEndian.Uint32(data[40:44]))
+
+	ticks := make([][]int16, 5)
+	for tick := range ticks {
+		ticks[tick] = make([]int16, chip8.SamplesPerTick)
+		for i := range ticks[tick] {
+			offset := 44 + (tick*chip8.SamplesPerTick+i)*2
+			ticks[tick][i] = int16(binary.LittleEndian.Uint16(data[offset:]))
+		}
+	}
+
+	silent := func(samples []int16) bool {
+		for _, s := range samples {
+			if s != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	require.True(t, silent(ticks[0]))
+	require.False(t, silent(ticks[1][:10]), "the tone starts with the tick")
+	require.False(t, silent(ticks[2]))
+	require.True(t, silent(ticks[3][chip8.SamplesPerTick/2:]), "the tone stops within the tick")
+	require.True(t, silent(ticks[4]))
+
+	// the tone ramps up instead of jumping to its full volume
+	require.True(t, ticks[1][0] < ticks[2][0])
+}
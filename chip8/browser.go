@@ -0,0 +1,307 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+const DefaultBrowserAddr = "localhost:8080"
+
+// Messages sent to the browsers over the WebSocket, as binary messages starting with their type.
+// The pixels of the screen are packed 8 per byte, the leftmost pixel in the highest bit.
+const (
+	msgFrame   = 0 // the 256 bytes of the screen
+	msgDelta   = 1 // pairs of an offset and the XOR of the byte at that offset with the previous screen
+	msgPalette = 2 // the background and foreground colors as RGB
+)
+
+// packedScreen is the screen with 8 pixels per byte.
+type packedScreen [DisplayWidth * DisplayHeight / 8]byte
+
+func (s *Screen) pack() packedScreen {
+	var packed packedScreen
+	for i, p := range s {
+		packed[i/8] |= p << uint(7-i%8)
+	}
+	return packed
+}
+
+// Browser is a frontend serving a page which shows the screen in a browser.
+// Any number of browsers can watch, only one of them controls the keys at a time.
+type Browser struct {
+	Addr string
+
+	emulator *Emulator
+	listener net.Listener
+	signals  chan os.Signal
+	pacer    pacer
+
+	mu         sync.Mutex
+	clients    map[*browserClient]bool
+	controller *browserClient
+	screen     packedScreen // last screen sent
+	theme      Theme        // last palette sent
+}
+
+// browserClient is a connected browser.
+type browserClient struct {
+	conn *websocket.Conn
+	send chan []byte
+	sync bool // the next frame must be sent whole
+}
+
+// browserEvent is a message sent by a browser.
+type browserEvent struct {
+	Type string `json:"type"` // keydown, keyup, control or release
+	Key  int    `json:"key"`
+}
+
+// browserStatus tells a browser if it controls the keys.
+type browserStatus struct {
+	Control   bool `json:"control"`
+	Available bool `json:"available"` // nobody controls the keys
+	Clients   int  `json:"clients"`
+}
+
+func NewBrowser(addr string) *Browser {
+	return &Browser{Addr: addr}
+}
+
+// Open starts serving the page.
+func (b *Browser) Open(emulator *Emulator) error {
+	listener, err := net.Listen("tcp", b.Addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to open browser frontend")
+	}
+	b.listener = listener
+	b.emulator = emulator
+	b.clients = make(map[*browserClient]bool)
+	b.theme = emulator.display.Theme()
+
+	page, err := browserPage(emulator.rom, emulator.keymap)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	})
+	mux.Handle("/ws", websocket.Server{Handler: b.serve, Handshake: sameOrigin})
+	go http.Serve(listener, mux)
+
+	b.signals = make(chan os.Signal, 1)
+	signal.Notify(b.signals, os.Interrupt, syscall.SIGTERM)
+	return nil
+}
+
+// Close stops serving and disconnects the browsers.
+func (b *Browser) Close() error {
+	signal.Stop(b.signals)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for client := range b.clients {
+		client.conn.Close()
+	}
+	return b.listener.Close()
+}
+
+// Update sends the screen to the browsers and feeds the keys of the controller to the emulator.
+func (b *Browser) Update() bool {
+	select {
+	case <-b.signals:
+		return false
+	default:
+	}
+
+	b.mu.Lock()
+	if theme := b.emulator.display.Theme(); theme != b.theme {
+		b.theme = theme
+		for client := range b.clients {
+			b.sendTo(client, paletteMessage(theme))
+		}
+	}
+	screen := b.emulator.display.memory.pack()
+	var delta []byte
+	if screen != b.screen {
+		delta = []byte{msgDelta}
+		for i := range screen {
+			if x := screen[i] ^ b.screen[i]; x != 0 {
+				delta = append(delta, byte(i), x)
+			}
+		}
+		b.screen = screen
+	}
+	for client := range b.clients {
+		switch {
+		case client.sync:
+			if b.sendTo(client, frameMessage(&screen)) {
+				client.sync = false
+			}
+		case delta != nil:
+			b.sendTo(client, delta)
+		}
+	}
+	b.mu.Unlock()
+
+	b.pacer.wait()
+	return true
+}
+
+// sendTo queues a message to a browser. A browser too slow to keep up skips frames and is sent the next one whole.
+func (b *Browser) sendTo(client *browserClient, msg []byte) bool {
+	select {
+	case client.send <- msg:
+		return true
+	default:
+		client.sync = true
+		return false
+	}
+}
+
+func frameMessage(screen *packedScreen) []byte {
+	return append([]byte{msgFrame}, screen[:]...)
+}
+
+func paletteMessage(theme Theme) []byte {
+	bg, fg := theme.Background, theme.Foreground
+	return []byte{msgPalette, bg.R, bg.G, bg.B, fg.R, fg.G, fg.B}
+}
+
+// serve handles the WebSocket of a browser.
+func (b *Browser) serve(conn *websocket.Conn) {
+	client := &browserClient{conn: conn, send: make(chan []byte, 16), sync: true}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range client.send {
+			var err error
+			if msg[0] == '{' { // the status is sent as JSON text
+				err = websocket.Message.Send(conn, string(msg))
+			} else {
+				err = websocket.Message.Send(conn, msg)
+			}
+			if err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	b.mu.Lock()
+	b.clients[client] = true
+	b.sendTo(client, paletteMessage(b.theme))
+	if b.controller == nil {
+		b.controller = client
+	}
+	b.broadcastStatus()
+	b.mu.Unlock()
+
+	for {
+		var event browserEvent
+		if err := websocket.JSON.Receive(conn, &event); err != nil {
+			break
+		}
+		b.mu.Lock()
+		b.handle(client, event)
+		b.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	delete(b.clients, client)
+	if b.controller == client {
+		b.controller = nil
+		b.emulator.keypad.ReleaseAll()
+	}
+	b.broadcastStatus()
+	close(client.send)
+	b.mu.Unlock()
+	<-done
+}
+
+// handle applies an event sent by a browser. It must be called with the lock held.
+func (b *Browser) handle(client *browserClient, event browserEvent) {
+	switch event.Type {
+	case "keydown", "keyup":
+		if client == b.controller {
+			b.emulator.keypad.Set(event.Key, event.Type == "keydown")
+		}
+	case "control":
+		if b.controller == nil {
+			b.controller = client
+			b.broadcastStatus()
+		}
+	case "release":
+		if client == b.controller {
+			b.controller = nil
+			b.emulator.keypad.ReleaseAll()
+			b.broadcastStatus()
+		}
+	}
+}
+
+// sameOrigin accepts the WebSockets opened by the page served, and refuses those opened by the pages of other sites,
+// which could otherwise take the control of the emulator from the browser of the user.
+func sameOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return errors.Wrap(err, "invalid origin")
+	}
+	if origin == nil || origin.Host != r.Host {
+		return errors.Errorf("origin %v not allowed for %s", origin, r.Host)
+	}
+	config.Origin = origin
+	return nil
+}
+
+// broadcastStatus tells every browser who controls the keys. It must be called with the lock held.
+func (b *Browser) broadcastStatus() {
+	for client := range b.clients {
+		status, _ := json.Marshal(browserStatus{
+			Control:   client == b.controller,
+			Available: b.controller == nil,
+			Clients:   len(b.clients),
+		})
+		b.sendTo(client, status)
+	}
+}
+
+// browserPage returns the page with the title of the ROM and the keys of the keymap.
+// The template escapes the title for the HTML and the keys for the script.
+func browserPage(rom *ROM, keymap Keymap) ([]byte, error) {
+	keys := make([]string, len(keymap.Keys))
+	for i, name := range keymap.Keys {
+		keys[i] = name
+		if code, ok := browserKeypadCodes[name]; ok {
+			keys[i] = code
+		}
+	}
+
+	var page bytes.Buffer
+	err := browserTemplate.Execute(&page, struct {
+		Title string
+		Keys  []string
+	}{rom.Name, keys})
+	return page.Bytes(), errors.Wrap(err, "failed to render page")
+}
+
+// browserKeypadCodes are the codes of the keys of the numeric keypad in the browsers.
+var browserKeypadCodes = map[string]string{
+	"KP0": "Numpad0", "KP1": "Numpad1", "KP2": "Numpad2", "KP3": "Numpad3", "KP4": "Numpad4",
+	"KP5": "Numpad5", "KP6": "Numpad6", "KP7": "Numpad7", "KP8": "Numpad8", "KP9": "Numpad9",
+	"KP/": "NumpadDivide", "KP*": "NumpadMultiply", "KP-": "NumpadSubtract", "KP+": "NumpadAdd",
+	"KP.": "NumpadDecimal", "KPEnter": "NumpadEnter",
+}
@@ -0,0 +1,94 @@
+package chip8
+
+import "html/template"
+
+var browserTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { background: #202020; color: #E0E0E0; font-family: sans-serif; text-align: center; }
+canvas { width: 640px; height: 320px; image-rendering: pixelated; image-rendering: crisp-edges; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<canvas id="screen" width="64" height="32"></canvas>
+<p><span id="status">connecting</span> <button id="control" disabled></button></p>
+<script>
+const KEYS = {{.Keys}};
+const canvas = document.getElementById("screen");
+const ctx = canvas.getContext("2d");
+const image = ctx.createImageData(64, 32);
+const status = document.getElementById("status");
+const control = document.getElementById("control");
+const screen = new Uint8Array(256);
+let palette = [[0, 0, 0], [255, 255, 255]];
+let controlling = false;
+
+function draw() {
+  for (let i = 0; i < 64 * 32; i++) {
+    const c = palette[(screen[i >> 3] >> (7 - (i & 7))) & 1];
+    image.data.set([c[0], c[1], c[2], 255], i * 4);
+  }
+  ctx.putImageData(image, 0, 0);
+}
+
+const ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws");
+ws.binaryType = "arraybuffer";
+ws.onmessage = (e) => {
+  if (typeof e.data === "string") {
+    const s = JSON.parse(e.data);
+    controlling = s.control;
+    status.textContent = (s.control ? "controlling" : "spectating") + ", " + s.clients + " connected";
+    control.textContent = s.control ? "Release control" : "Take control";
+    control.disabled = !s.control && !s.available;
+    return;
+  }
+  const msg = new Uint8Array(e.data);
+  switch (msg[0]) {
+  case 0:
+    screen.set(msg.subarray(1));
+    break;
+  case 1:
+    for (let i = 1; i + 1 < msg.length; i += 2) {
+      screen[msg[i]] ^= msg[i + 1];
+    }
+    break;
+  case 2:
+    palette = [[msg[1], msg[2], msg[3]], [msg[4], msg[5], msg[6]]];
+    break;
+  }
+  draw();
+};
+ws.onclose = () => { status.textContent = "disconnected"; control.disabled = true; };
+
+control.onclick = () => ws.send(JSON.stringify({type: controlling ? "release" : "control"}));
+
+function key(type) {
+  return (e) => {
+    // the keys of the numeric keypad are told apart by their code, the number row by its digits whatever the layout
+    // types, the others by the character printed on them
+    let name = e.key.toUpperCase();
+    if (e.code.startsWith("Numpad")) {
+      name = e.code;
+    } else if (e.code.startsWith("Digit")) {
+      name = e.code.slice(5);
+    }
+    const k = KEYS.indexOf(name);
+    if (k < 0 || e.repeat) {
+      return;
+    }
+    e.preventDefault();
+    if (controlling) {
+      ws.send(JSON.stringify({type: type, key: k}));
+    }
+  };
+}
+document.addEventListener("keydown", key("keydown"));
+document.addEventListener("keyup", key("keyup"));
+</script>
+</body>
+</html>
+`))
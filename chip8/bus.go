@@ -0,0 +1,307 @@
+package chip8
+
+import "github.com/pkg/errors"
+
+// Bus is the memory as seen by the program: the instructions read and write the bytes of the memory through it.
+type Bus interface {
+	Read(addr uint16) byte
+	Write(addr uint16, b byte)
+}
+
+// Peeker is a bus or a device telling the byte at an address without the side effects of reading it,
+// such as for the debuggers and the hooks. The RAM, the mirrors, the banks and the buses wrapping them are peekers.
+type Peeker interface {
+	Peek(addr uint16) byte
+}
+
+// peek returns the byte at an address of a bus without side effects, or 0 when the bus can't tell it.
+func peek(bus Bus, addr uint16) byte {
+	if p, ok := bus.(Peeker); ok {
+		return p.Peek(addr)
+	}
+	return 0
+}
+
+// MemoryBus is the RAM, with devices mapped over ranges of its addresses.
+// A device reads and writes the offsets in its range, from 0.
+type MemoryBus struct {
+	ram     *RAM
+	regions []region // the last mapped first
+}
+
+// region is a device mapped on the addresses from start to end, excluded.
+type region struct {
+	start, end int
+	device     Bus
+}
+
+func NewMemoryBus(ram *RAM) *MemoryBus {
+	return &MemoryBus{ram: ram}
+}
+
+// Map maps a device on size bytes from an address, over the RAM and the devices mapped before.
+// A mirror must repeat a range of the memory, out of its own range when it repeats the bus, even through other buses.
+func (bus *MemoryBus) Map(start uint16, size int, device Bus) error {
+	if size <= 0 || int(start)+size > RamSize {
+		return errors.Errorf("%d bytes at %#x out of memory", size, start)
+	}
+	if m, ok := device.(Mirror); ok {
+		if err := m.Check(); err != nil {
+			return err
+		}
+		if base, end, ok := m.repeats(bus); ok && base < int(start)+size && end > int(start) {
+			return errors.Errorf("mirror of %d bytes at %#x over itself at %#x", m.Size, m.Base, start)
+		}
+	}
+	bus.regions = append([]region{{int(start), int(start) + size, device}}, bus.regions...)
+	return nil
+}
+
+// Wrapper is a bus reading and writing through another bus, at the same addresses or not.
+type Wrapper interface {
+	Unwrap() Bus
+}
+
+// Unmap removes the device mapped last on an address, if any.
+func (bus *MemoryBus) Unmap(addr uint16) {
+	for i, r := range bus.regions {
+		if int(addr) >= r.start && int(addr) < r.end {
+			bus.regions = append(bus.regions[:i], bus.regions[i+1:]...)
+			return
+		}
+	}
+}
+
+// Protect makes size bytes of the RAM from an address read-only, such as the font or the ROM.
+func (bus *MemoryBus) Protect(start uint16, size int) error {
+	return bus.Map(start, size, ReadOnly{ramRange{bus, start}})
+}
+
+// device returns the device mapped on an address and the offset of the address in its range,
+// or the RAM and the address.
+func (bus *MemoryBus) device(addr uint16) (Bus, uint16) {
+	for _, r := range bus.regions {
+		if int(addr) >= r.start && int(addr) < r.end {
+			return r.device, addr - uint16(r.start)
+		}
+	}
+	return bus.ram, addr
+}
+
+// Mapped tells if a device is mapped on an address.
+func (bus *MemoryBus) Mapped(addr uint16) bool {
+	device, _ := bus.device(addr)
+	return device != Bus(bus.ram)
+}
+
+// Read returns the byte at an address.
+func (bus *MemoryBus) Read(addr uint16) byte {
+	device, offset := bus.device(addr)
+	return device.Read(offset)
+}
+
+// Peek returns the byte at an address without side effects, 0 when the device mapped there can't tell it.
+func (bus *MemoryBus) Peek(addr uint16) byte {
+	device, offset := bus.device(addr)
+	return peek(device, offset)
+}
+
+// Write stores a byte at an address.
+func (bus *MemoryBus) Write(addr uint16, b byte) {
+	device, offset := bus.device(addr)
+	device.Write(offset, b)
+}
+
+// ramRange is the RAM of a bus from an address, the RAM being replaced when the emulator is reset.
+type ramRange struct {
+	bus  *MemoryBus
+	base uint16
+}
+
+func (r ramRange) Read(offset uint16) byte {
+	return r.bus.ram.Read(r.base + offset)
+}
+
+func (r ramRange) Peek(offset uint16) byte {
+	return r.Read(offset)
+}
+
+func (r ramRange) Write(offset uint16, b byte) {
+	r.bus.ram.Write(r.base+offset, b)
+}
+
+// ReadOnly is a device ignoring the writes.
+type ReadOnly struct{ Bus }
+
+// Write does nothing.
+func (ReadOnly) Write(addr uint16, b byte) {}
+
+func (r ReadOnly) Unwrap() Bus {
+	return r.Bus
+}
+
+func (r ReadOnly) Peek(addr uint16) byte {
+	return peek(r.Bus, addr)
+}
+
+// Mirror repeats Size bytes of another bus from Base over its range.
+type Mirror struct {
+	Bus        Bus
+	Base, Size uint16
+}
+
+// NewMirror returns the mirror of size bytes of a bus from an address, or an error when they are out of the memory.
+func NewMirror(bus Bus, base, size uint16) (Mirror, error) {
+	m := Mirror{Bus: bus, Base: base, Size: size}
+	return m, m.Check()
+}
+
+// Check returns an error when the range repeated is empty or out of the memory.
+func (m Mirror) Check() error {
+	if m.Size == 0 || int(m.Base)+int(m.Size) > RamSize {
+		return errors.Errorf("mirror of %d bytes at %#x out of memory", m.Size, m.Base)
+	}
+	return nil
+}
+
+func (m Mirror) Unwrap() Bus {
+	return m.Bus
+}
+
+// repeats returns the range of the addresses of a bus the mirror may read, if it reads the bus through its wrapped buses.
+func (m Mirror) repeats(bus Bus) (base, end int, ok bool) {
+	base, end = int(m.Base), int(m.Base)+int(m.Size)
+	for inner := m.Bus; inner != nil; {
+		if inner == bus {
+			return base, end, true
+		}
+		if mirror, ok := inner.(Mirror); ok {
+			base, end = int(mirror.Base), int(mirror.Base)+int(mirror.Size)
+		}
+		wrapper, ok := inner.(Wrapper)
+		if !ok {
+			break
+		}
+		inner = wrapper.Unwrap()
+	}
+	return 0, 0, false
+}
+
+// Read returns the byte repeated at an offset, 0 when the mirror is empty.
+func (m Mirror) Read(offset uint16) byte {
+	if m.Size == 0 {
+		return 0
+	}
+	return m.Bus.Read(m.Base + offset%m.Size)
+}
+
+// Peek returns the byte repeated at an offset without side effects, 0 when the mirror is empty.
+func (m Mirror) Peek(offset uint16) byte {
+	if m.Size == 0 {
+		return 0
+	}
+	return peek(m.Bus, m.Base+offset%m.Size)
+}
+
+// Write stores the byte repeated at an offset, nothing when the mirror is empty.
+func (m Mirror) Write(offset uint16, b byte) {
+	if m.Size == 0 {
+		return
+	}
+	m.Bus.Write(m.Base+offset%m.Size, b)
+}
+
+// Banked is memory of which a bank is selected at once, the offsets out of the bank reading 0.
+type Banked struct {
+	Banks [][]byte
+	Bank  int // bank selected
+}
+
+// bank returns the bank selected, or nil.
+func (b *Banked) bank() []byte {
+	if b.Bank < 0 || b.Bank >= len(b.Banks) {
+		return nil
+	}
+	return b.Banks[b.Bank]
+}
+
+func (b *Banked) Read(offset uint16) byte {
+	if bank := b.bank(); int(offset) < len(bank) {
+		return bank[offset]
+	}
+	return 0
+}
+
+func (b *Banked) Peek(offset uint16) byte {
+	return b.Read(offset)
+}
+
+func (b *Banked) Write(offset uint16, v byte) {
+	if bank := b.bank(); int(offset) < len(bank) {
+		bank[offset] = v
+	}
+}
+
+// IO is a device calling functions on the reads and the writes, such as memory-mapped registers.
+// A read without function is 0, a write without function does nothing. It isn't a Peeker, its reads have side effects.
+type IO struct {
+	OnRead  func(offset uint16) byte
+	OnWrite func(offset uint16, b byte)
+}
+
+func (io IO) Read(offset uint16) byte {
+	if io.OnRead == nil {
+		return 0
+	}
+	return io.OnRead(offset)
+}
+
+func (io IO) Write(offset uint16, b byte) {
+	if io.OnWrite != nil {
+		io.OnWrite(offset, b)
+	}
+}
+
+// Counter counts the reads and the writes of every address of a bus.
+type Counter struct {
+	Bus
+	Reads, Writes [RamSize]int64
+}
+
+func (c *Counter) Read(addr uint16) byte {
+	if addr < RamSize {
+		c.Reads[addr]++
+	}
+	return c.Bus.Read(addr)
+}
+
+func (c *Counter) Unwrap() Bus {
+	return c.Bus
+}
+
+// Peek returns the byte at an address of the bus without counting it.
+func (c *Counter) Peek(addr uint16) byte {
+	return peek(c.Bus, addr)
+}
+
+func (c *Counter) Write(addr uint16, b byte) {
+	if addr < RamSize {
+		c.Writes[addr]++
+	}
+	c.Bus.Write(addr, b)
+}
+
+// Bus returns the bus the program accesses the memory through.
+func (emulator *Emulator) Bus() Bus {
+	return emulator.bus
+}
+
+// SetBus sets the bus the program accesses the memory through, such as a Counter of the bus.
+func (emulator *Emulator) SetBus(bus Bus) {
+	emulator.bus = bus
+}
+
+// MemoryBus returns the RAM on which devices are mapped, the bus of the program by default.
+func (emulator *Emulator) MemoryBus() *MemoryBus {
+	return emulator.memory
+}
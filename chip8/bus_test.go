@@ -0,0 +1,86 @@
+package chip8_test
+
+import (
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0x60, 0x42, // V0 = 0x42
+		0xA0, 0x00, // I = 0
+		0xF0, 0x55, // store V0 at I, in the font
+		0xAE, 0x10, // I = 0xE10
+		0xF0, 0x55, // store V0 at I, in the mirror
+		0xAF, 0x00, // I = 0xF00
+		0xF0, 0x55, // store V0 at I, in the bank
+		0xAF, 0xF0, // I = 0xFF0
+		0xF0, 0x55, // store V0 at I, in the device
+		0xF1, 0x65, // read V0 and V1 from I
+		0x12, 0x16, // jump to itself
+	}}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.SetSpeed(11)
+	emulator.Reset()
+
+	memory := emulator.MemoryBus()
+	require.NoError(t, memory.Protect(0, chip8.ProgramLocation))
+	require.NoError(t, memory.Map(0xE00, 0x100, chip8.Mirror{Bus: memory, Base: 0x300, Size: 0x10}))
+	banked := &chip8.Banked{Banks: [][]byte{make([]byte, 0x10), make([]byte, 0x10)}, Bank: 1}
+	require.NoError(t, memory.Map(0xF00, 0x10, banked))
+	var written []byte
+	require.NoError(t, memory.Map(0xFF0, 0x10, chip8.IO{
+		OnRead:  func(offset uint16) byte { return byte(offset) + 0x10 },
+		OnWrite: func(offset uint16, b byte) { written = append(written, byte(offset), b) },
+	}))
+	require.Error(t, memory.Map(0xFFF, 2, chip8.IO{}))
+	require.Error(t, memory.Map(0xD00, 0x10, chip8.Mirror{Bus: memory, Base: 0x300}))
+	require.Error(t, memory.Map(0xD00, 0x10, chip8.Mirror{Bus: memory, Base: 0xFFF0, Size: 0x20}))
+	require.Error(t, memory.Map(0xD00, 0x10, chip8.Mirror{Bus: memory, Base: 0xFF0, Size: 0x20}))
+	require.Error(t, memory.Map(0xD00, 0x10, chip8.Mirror{Bus: memory, Base: 0xCF8, Size: 0x10}))
+	wrapped := &chip8.Counter{Bus: emulator.Bus()}
+	require.Error(t, memory.Map(0xD00, 0x10, chip8.Mirror{Bus: wrapped, Base: 0xD08, Size: 0x10}))
+	require.Error(t, memory.Map(0xD00, 0x10, chip8.Mirror{Bus: chip8.ReadOnly{Bus: wrapped}, Base: 0xD00, Size: 4}))
+	require.Error(t, memory.Map(0xD00, 0x10, chip8.Mirror{Bus: chip8.Mirror{Bus: memory, Base: 0xD04, Size: 8}, Size: 4}))
+	require.NoError(t, memory.Map(0xD00, 0x10, chip8.Mirror{Bus: chip8.Mirror{Bus: wrapped, Base: 0x300, Size: 8}, Size: 4}))
+	memory.Unmap(0xD00)
+	_, err := chip8.NewMirror(memory, 0x300, 0)
+	require.Error(t, err)
+	require.Equal(t, byte(0), chip8.Mirror{Bus: memory}.Read(0x10))
+	counter := &chip8.Counter{Bus: emulator.Bus()}
+	emulator.SetBus(counter)
+
+	require.True(t, emulator.Frame())
+	require.Equal(t, chip8.Font[0], emulator.State().RAM[0])
+	require.Equal(t, byte(0x42), emulator.State().RAM[0x300])
+	require.Equal(t, []byte{0, 0x42}, []byte{banked.Banks[0][0], banked.Banks[1][0]})
+	require.Equal(t, []byte{0, 0x42}, written)
+	require.Equal(t, uint8(0x10), emulator.State().V[0])
+	require.Equal(t, uint8(0x11), emulator.State().V[1])
+	require.Equal(t, int64(1), counter.Reads[0x200])
+	require.Equal(t, int64(1), counter.Writes[0xE10])
+
+	// Peek reads the RAM under the devices, without counting
+	b, err := emulator.Peek(0xE20)
+	require.NoError(t, err)
+	require.Equal(t, byte(0), b)
+	b, err = emulator.Peek(0xFF0)
+	require.NoError(t, err)
+	require.Equal(t, byte(0), b)
+	require.Equal(t, []byte{0, 0x42}, written)
+	require.Equal(t, int64(0), counter.Reads[0xE20])
+
+	// the devices stay mapped after a reset
+	emulator.Reset()
+	require.Error(t, emulator.Poke(0x10, 0xFF))
+	require.Error(t, emulator.SetMemory(0x0E, []byte{1, 2, 3}))
+	data, err := emulator.Memory(0x0E, 3)
+	require.NoError(t, err)
+	require.Equal(t, chip8.Font[0x0E:0x11], data)
+	memory.Unmap(0x10)
+	require.NoError(t, emulator.Poke(0x10, 0xFF))
+	require.Equal(t, byte(0xFF), emulator.State().RAM[0x10])
+}
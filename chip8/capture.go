@@ -0,0 +1,127 @@
+package chip8
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const DefaultCaptureScale = 10
+
+// Capture configures the screenshots and the recordings of the display.
+// Frames are counted from 1, at 60 per second.
+type Capture struct {
+	Scale           int    // size of the pixels in the captured images
+	Screenshot      string // PNG file of the screen
+	ScreenshotFrame int    // frame of the screenshot, 0 for when the emulator stops
+	GIF             string // GIF file of the recording
+	GIFFrom         int    // first frame of the recording
+	GIFFrames       int    // number of frames recorded, 0 for until the emulator stops
+}
+
+// Hash returns the SHA-1 of the pixels of the screen in hexadecimal, to compare screens.
+func (screen *Screen) Hash() string {
+	sum := sha1.Sum(screen[:])
+	return hex.EncodeToString(sum[:])
+}
+
+// SavePNG writes the screen into a PNG file.
+func (screen *Screen) SavePNG(filename string, theme Theme, scale int) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to save screenshot")
+	}
+	defer file.Close()
+	if err := png.Encode(file, screen.Paletted(theme, scale)); err != nil {
+		return errors.Wrap(err, "failed to save screenshot")
+	}
+	return file.Close()
+}
+
+// Recorder records the frames of the display into an animated GIF.
+type Recorder struct {
+	theme  Theme
+	scale  int
+	screen []Screen // successive different screens
+	frames []int    // number of frames each screen is shown
+}
+
+func NewRecorder(theme Theme, scale int) *Recorder {
+	return &Recorder{theme: theme, scale: scale}
+}
+
+// Record adds a frame to the recording.
+func (r *Recorder) Record(screen *Screen) {
+	if n := len(r.screen); n > 0 && r.screen[n-1] == *screen {
+		r.frames[n-1]++
+		return
+	}
+	r.screen = append(r.screen, *screen)
+	r.frames = append(r.frames, 1)
+}
+
+// Frames returns the number of frames recorded.
+func (r *Recorder) Frames() int {
+	frames := 0
+	for _, n := range r.frames {
+		frames += n
+	}
+	return frames
+}
+
+// GIF returns the recording.
+// The delays are in hundredths of a second, rounded so they add up to the time elapsed at 60 frames per second.
+// The viewers slow down the delays under 2, so a screen which would get less is folded into the next one,
+// or into the one before for the last.
+func (r *Recorder) GIF() *gif.GIF {
+	g := &gif.GIF{}
+	elapsed, start := 0, 0.0
+	last := -1 // the screen of the last image
+	for i := range r.screen {
+		elapsed += r.frames[i]
+		end := math.Floor(float64(elapsed)*100/TimerFrequency + 0.5)
+		delay := int(end - start)
+		switch {
+		case delay < minDelay && i < len(r.screen)-1:
+			continue
+		case last >= 0 && (delay < minDelay || r.screen[i] == r.screen[last]):
+			g.Delay[len(g.Delay)-1] += delay
+		default:
+			g.Image = append(g.Image, r.screen[i].Paletted(r.theme, r.scale))
+			g.Delay = append(g.Delay, delay)
+			last = i
+		}
+		start = end
+	}
+	return g
+}
+
+// minDelay is the shortest delay of an image of a GIF, in hundredths of a second, played as is by the viewers.
+const minDelay = 2
+
+// Save writes the recording into a GIF file.
+func (r *Recorder) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to save recording")
+	}
+	defer file.Close()
+	if err := gif.EncodeAll(file, r.GIF()); err != nil {
+		return errors.Wrap(err, "failed to save recording")
+	}
+	return file.Close()
+}
+
+// captureName returns the name of a file captured with a hotkey.
+func captureName(rom *ROM, frame int, ext string) string {
+	name := strings.TrimSuffix(rom.Name, path.Ext(rom.Name))
+	return fmt.Sprintf("%s-%06d%s", name, frame, ext)
+}
@@ -0,0 +1,100 @@
+package chip8_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaletted(t *testing.T) {
+	var screen chip8.Screen
+	screen[1] = 1
+
+	theme := chip8.Themes[0]
+	img := screen.Paletted(theme, 3)
+	require.Equal(t, 64*3, img.Bounds().Dx())
+	require.Equal(t, 32*3, img.Bounds().Dy())
+	require.Equal(t, theme.Background, img.At(2, 2))
+	require.Equal(t, theme.Foreground, img.At(3, 2))
+	require.Equal(t, theme.Foreground, img.At(5, 2))
+	require.Equal(t, theme.Background, img.At(3, 3))
+}
+
+func TestScreenImage(t *testing.T) {
+	var screen chip8.Screen
+	screen[chip8.DisplayWidth+2] = 1
+
+	var img image.PalettedImage = &screen
+	require.Equal(t, image.Rect(0, 0, 64, 32), img.Bounds())
+	require.Equal(t, uint8(1), img.ColorIndexAt(2, 1))
+	require.Equal(t, color.White, img.At(2, 1))
+	require.Equal(t, color.Black, img.At(1, 2))
+	require.Equal(t, color.Black, img.At(64, 0))
+
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, &screen))
+	decoded, err := png.Decode(&encoded)
+	require.NoError(t, err)
+	r, _, _, _ := decoded.At(2, 1).RGBA()
+	require.Equal(t, uint32(0xFFFF), r)
+
+	scaled := screen.Image(nil, 2)
+	require.Equal(t, image.Rect(0, 0, 128, 64), scaled.Bounds())
+	require.Equal(t, uint8(1), scaled.ColorIndexAt(5, 3))
+	require.Equal(t, uint8(0), scaled.ColorIndexAt(6, 3))
+
+	palette := color.Palette{color.RGBA{0, 0, 0x80, 0xFF}, color.RGBA{0xFF, 0xFF, 0, 0xFF}}
+	require.Equal(t, palette[1], screen.Image(palette, 1).At(2, 1))
+
+	lines := strings.Split(screen.ASCII(), "\n")
+	require.Len(t, lines, 33)
+	require.Equal(t, "..#"+strings.Repeat(".", 61), lines[1])
+	require.True(t, strings.HasPrefix(strings.Split(screen.Text("██", "  "), "\n")[1], "    ██  "))
+}
+
+func TestRecorder(t *testing.T) {
+	var a, b chip8.Screen
+	b[0] = 1
+
+	recorder := chip8.NewRecorder(chip8.Themes[0], 1)
+	for _, screen := range []*chip8.Screen{&a, &b, &a, &a, &a, &b} {
+		recorder.Record(screen)
+	}
+	require.Equal(t, 6, recorder.Frames())
+
+	g := recorder.GIF()
+	require.Len(t, g.Image, 2)
+	require.Equal(t, []int{8, 2}, g.Delay)
+}
+
+func TestRecorderChanging(t *testing.T) {
+	var a, b chip8.Screen
+	b[0] = 1
+
+	recorder := chip8.NewRecorder(chip8.Themes[0], 1)
+	for i := 0; i < 60; i++ {
+		if i%2 == 0 {
+			recorder.Record(&a)
+		} else {
+			recorder.Record(&b)
+		}
+	}
+
+	g := recorder.GIF()
+	require.Len(t, g.Image, len(g.Delay))
+	total := 0
+	for i, delay := range g.Delay {
+		require.True(t, delay >= 2, "delays %v", g.Delay)
+		total += delay
+		if i > 0 {
+			require.NotEqual(t, g.Image[i-1].Pix, g.Image[i].Pix)
+		}
+	}
+	require.Equal(t, 100, total)
+}
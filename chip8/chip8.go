@@ -1,28 +1,24 @@
 package chip8
 
-import "github.com/faiface/pixel/pixelgl"
-
 const (
 	ProgramLocation = 0x200
 	RamSize         = 4 * 1024 // 4KB
+	MaxRomSize      = RamSize - ProgramLocation
 	RegSize         = 16
 	StackSize       = 16
 	KeyboardSize    = 16
 	InstructionSize = 2
 	SpriteSize      = 5
 
+	TimerFrequency       = 60   // Hz
+	InstructionsPerFrame = 12   // instructions executed between two ticks of the timers
+	MaxSpeed             = 1024 // instructions per frame at most
+
 	DisplayWidth       = 64
 	DisplayHeight      = 32
-	DisplayScaleFactor = 20
+	DisplayScaleFactor = 20 // initial size of the window
 )
 
-var Keys = []pixelgl.Button{
-	pixelgl.KeyX, pixelgl.Key1, pixelgl.Key2, pixelgl.Key3,
-	pixelgl.KeyQ, pixelgl.KeyW, pixelgl.KeyE, pixelgl.KeyA,
-	pixelgl.KeyS, pixelgl.KeyD, pixelgl.KeyZ, pixelgl.KeyC,
-	pixelgl.Key4, pixelgl.KeyR, pixelgl.KeyF, pixelgl.KeyV,
-}
-
 var Font = [80]byte{
 	0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
 	0x20, 0x60, 0x20, 0x20, 0x70, // 1
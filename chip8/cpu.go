@@ -20,21 +20,29 @@ func (cpu *CPU) UpdateTimers() {
 		cpu.dt--
 	}
 	if cpu.st > 0 {
-		if cpu.st == 1 {
-			// TODO: play sound
-		}
 		cpu.st--
 	}
 }
 
+// Buzzing tells if the buzzer is on, which is the case as long as the sound timer is active.
+func (cpu *CPU) Buzzing() bool {
+	return cpu.st > 0
+}
+
 func (cpu *CPU) ReadInstruction(emulator *Emulator) Instruction {
 	// read 2 bytes integer in little endian format
-	val := (uint16(emulator.ram.data[cpu.pc]) << 8) | uint16(emulator.ram.data[cpu.pc+1])
+	val := (uint16(emulator.bus.Read(cpu.pc)) << 8) | uint16(emulator.bus.Read(cpu.pc+1))
 	if val == 0 {
 		return nil
 	}
-	instruction := &BaseInstruction{emulator: emulator, val: val, addr: cpu.pc}
+	instruction := decode(emulator, cpu.pc, val)
 	cpu.pc += InstructionSize
+	return instruction
+}
+
+// decode returns the instruction of a value read at an address.
+func decode(emulator *Emulator, addr, val uint16) Instruction {
+	instruction := &BaseInstruction{emulator: emulator, val: val, addr: addr}
 
 	switch (val >> 12) & 0xF {
 	case 0x0:
@@ -118,5 +126,14 @@ func (cpu *CPU) ReadInstruction(emulator *Emulator) Instruction {
 			return &ReadMemory{instruction}
 		}
 	}
-	return instruction
+	if val>>12 == 0x0 {
+		return instruction // SYS addr, ignored by the interpreters
+	}
+	return &Unknown{instruction}
+}
+
+// Instruction returns the instruction at an address of the memory, without executing it.
+func (emulator *Emulator) Instruction(addr uint16) Instruction {
+	ram := emulator.ram
+	return decode(emulator, addr, uint16(ram.Read(addr))<<8|uint16(ram.Read(addr+1)))
 }
@@ -1,76 +1,96 @@
 package chip8
 
-import (
-	"os"
-
-	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/imdraw"
-	"github.com/faiface/pixel/pixelgl"
-	"golang.org/x/image/colornames"
-)
+import "image/color"
 
+// Display is the memory of the screen, and the settings of the frontends showing it.
 type Display struct {
-	config pixelgl.WindowConfig
-	window *pixelgl.Window
-	memory [DisplayWidth * DisplayHeight]byte
+	memory Screen
+	dirty  bool // the memory or the settings changed since the window last drew them
+	theme  int  // index in Themes, or -1 for a custom theme
+	custom Theme
+	style  PixelStyle
+	filter DisplayFilter
 }
 
 func NewDisplay() *Display {
-	config := pixelgl.WindowConfig{
-		Bounds: pixel.R(
-			0, 0,
-			DisplayWidth*DisplayScaleFactor,
-			DisplayHeight*DisplayScaleFactor,
-		),
-		VSync: true,
+	return &Display{filter: NoFilter{}}
+}
+
+func (display *Display) Clear() {
+	for i := 0; i < DisplayWidth*DisplayHeight; i++ {
+		display.memory[i] = 0
 	}
-	return &Display{config: config}
+	display.dirty = true
 }
 
-func (display *Display) Init() {
-	window, err := pixelgl.NewWindow(display.config)
-	if err != nil {
-		panic(err)
+// Theme returns the palette of the display.
+func (display *Display) Theme() Theme {
+	if display.theme < 0 {
+		return display.custom
 	}
-	display.window = window
+	return Themes[display.theme]
+}
 
-	go func() {
-		for !display.window.Closed() {
+// SetTheme changes the palette of the display.
+func (display *Display) SetTheme(theme Theme) {
+	display.theme = -1
+	display.custom = theme
+	for i, t := range Themes {
+		if t == theme {
+			display.theme = i
 		}
-		os.Exit(0)
-	}()
+	}
+	display.dirty = true
+}
 
-	go func() {
-		for {
-			display.Update()
-		}
-	}()
+// NextTheme switches to the next builtin theme.
+func (display *Display) NextTheme() {
+	display.theme = (display.theme + 1) % len(Themes)
+	display.dirty = true
 }
 
-func (display *Display) Clear() {
-	for i := 0; i < DisplayWidth*DisplayHeight; i++ {
-		display.memory[i] = 0
-	}
+// SetPixelStyle changes the shape of the pixels.
+func (display *Display) SetPixelStyle(style PixelStyle) {
+	display.style = style
+	display.dirty = true
 }
 
-func (display *Display) Update() {
-	display.window.Clear(colornames.Greenyellow)
-	for x := 0; x < DisplayWidth; x++ {
-		for y := 0; y < DisplayHeight; y++ {
-			if display.memory[y*DisplayWidth+x] == 1 {
-				x := float64(x * DisplayScaleFactor)
-				y := float64((DisplayHeight - y) * DisplayScaleFactor)
+// NextPixelStyle switches to the next pixel style.
+func (display *Display) NextPixelStyle() {
+	display.style = (display.style + 1) % pixelStyles
+	display.dirty = true
+}
 
-				cube := imdraw.New(nil)
-				cube.Color = colornames.Black
-				cube.Push(pixel.V(x, y))
-				cube.Push(pixel.V(x, y-DisplayScaleFactor))
-				cube.Push(pixel.V(x+DisplayScaleFactor, y-DisplayScaleFactor))
-				cube.Push(pixel.V(x+DisplayScaleFactor, y))
-				cube.Polygon(0)
-				cube.Draw(display.window)
-			}
-		}
+// PixelStyle returns the shape of the pixels.
+func (display *Display) PixelStyle() PixelStyle {
+	return display.style
+}
+
+// Filter applies the filter of the display to the memory, setting the brightness of the pixels to show.
+func (display *Display) Filter(brightness *Brightness) {
+	display.filter.Filter(&display.memory, brightness)
+}
+
+// Dirty tells if the memory or the settings changed since the frontend last drew them.
+func (display *Display) Dirty() bool {
+	return display.dirty
+}
+
+// SetDirty marks the display to be drawn again by the frontend, or drawn when dirty is false.
+func (display *Display) SetDirty(dirty bool) {
+	display.dirty = dirty
+}
+
+// SetFilter changes the filter applied to the frames before they are shown.
+func (display *Display) SetFilter(filter DisplayFilter) {
+	display.filter = filter
+	display.dirty = true
+}
+
+// MixRGBA returns the color at t between a and b.
+func MixRGBA(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5)
 	}
-	display.window.Update()
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
 }
@@ -2,50 +2,437 @@ package chip8
 
 import (
 	"fmt"
+	"io"
+	"math/rand"
+	"os"
 	"time"
 
-	"github.com/faiface/pixel/pixelgl"
+	"github.com/pkg/errors"
 )
 
 type Emulator struct {
-	keys    [KeyboardSize]bool
+	keypad  *Keypad
+	wait    keyWait
+	yield   bool // the program waits, the rest of the frame is skipped
 	display *Display
 	ram     *RAM
+	memory  *MemoryBus // RAM with the devices mapped
+	bus     Bus        // accesses of the program, the memory by default
 	cpu     *CPU
 	rom     *ROM
+	audio   AudioSink
+	frame   int
+	trace   io.Writer
+	quirks  Quirks
+	speed   int // instructions per frame
+	rand    *rand.Rand
+	paused  bool
+	advance int // frames to execute while paused
+	muted   bool
+
+	current  Instruction // last instruction executed
+	executed int64       // instructions executed since the emulator was created
+
+	fault *Fault // error which stopped the program
+
+	detectors HaltDetectors
+	halt      *Halt                // end of the program detected
+	loops     map[uint16]loopState // state at the jumps back, by address of the jump
+	changes   int64                // writes, draws and random numbers, which change the state of the loops
+
+	hooks  Hooks
+	timers [2]bool // delay and sound timers active, at the last call of the timer hook
+
+	breakpoints map[uint16]bool
+	hit         bool // the emulation stopped on the breakpoint at PC, which is passed when it resumes
+
+	keymap       Keymap
+	keymaps      *KeymapConfig
+	keymapConfig string // file of the keymaps, where the keymaps rebound are saved
+
+	capture   Capture
+	recorder  *Recorder // recording of the capture
+	recording *Recorder // recording started with the hotkey
 }
 
 func NewEmulator(rom *ROM) *Emulator {
+	ram := NewRAM()
+	memory := NewMemoryBus(ram)
 	return &Emulator{
+		keypad:  &Keypad{},
 		display: NewDisplay(),
-		ram:     NewRAM(),
+		ram:     ram,
+		memory:  memory,
+		bus:     memory,
 		cpu:     NewCPU(),
 		rom:     rom,
+		audio:   NullSink{},
+		trace:   os.Stdout,
+		speed:   InstructionsPerFrame,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		keymap:  Keymaps[0],
+		capture: Capture{Scale: DefaultCaptureScale},
+
+		breakpoints: make(map[uint16]bool),
+		loops:       make(map[uint16]loopState),
+	}
+}
+
+// SetAudioSink sets where the buzzer is played. The sink is closed when the emulator stops.
+func (emulator *Emulator) SetAudioSink(sink AudioSink) {
+	emulator.audio = sink
+}
+
+// SetTheme sets the palette of the display.
+func (emulator *Emulator) SetTheme(theme Theme) {
+	emulator.display.SetTheme(theme)
+}
+
+// SetPixelStyle sets the shape of the pixels of the display.
+func (emulator *Emulator) SetPixelStyle(style PixelStyle) {
+	emulator.display.SetPixelStyle(style)
+}
+
+// SetFilter sets the filter reducing the flickering of the display.
+func (emulator *Emulator) SetFilter(filter DisplayFilter) {
+	emulator.display.SetFilter(filter)
+}
+
+// SetCapture sets the screenshots and the recordings taken while running.
+func (emulator *Emulator) SetCapture(capture Capture) {
+	if capture.Scale <= 0 {
+		capture.Scale = DefaultCaptureScale
+	}
+	emulator.capture = capture
+}
+
+// SetTrace sets where the executed instructions are printed, nil to not print them.
+func (emulator *Emulator) SetTrace(trace io.Writer) {
+	emulator.trace = trace
+}
+
+// SetQuirks sets the behavior of the instructions the interpreters disagree on.
+func (emulator *Emulator) SetQuirks(quirks Quirks) {
+	emulator.quirks = quirks
+}
+
+// SetSpeed sets the number of instructions executed per frame, InstructionsPerFrame by default, up to MaxSpeed.
+func (emulator *Emulator) SetSpeed(instructions int) {
+	if instructions <= 0 {
+		instructions = InstructionsPerFrame
+	}
+	if instructions > MaxSpeed {
+		instructions = MaxSpeed
+	}
+	emulator.speed = instructions
+}
+
+// SetSeed seeds the random numbers of RND, so that the emulation can be replayed.
+func (emulator *Emulator) SetSeed(seed int64) {
+	emulator.rand = rand.New(rand.NewSource(seed))
+}
+
+// Instructions returns the number of instructions executed since the emulator was created.
+func (emulator *Emulator) Instructions() int64 {
+	return emulator.executed
+}
+
+// Speed returns the number of instructions executed per frame.
+func (emulator *Emulator) Speed() int {
+	return emulator.speed
+}
+
+// SetPaused pauses or resumes the emulation. The frames are skipped while paused, but for those advanced.
+func (emulator *Emulator) SetPaused(paused bool) {
+	emulator.paused = paused
+	emulator.advance = 0
+}
+
+// Paused tells if the emulation is paused.
+func (emulator *Emulator) Paused() bool {
+	return emulator.paused
+}
+
+// Advance executes the next frame while paused.
+func (emulator *Emulator) Advance() {
+	if emulator.paused {
+		emulator.advance++
+	}
+}
+
+// SetBreakpoint sets or clears a breakpoint on an address.
+// The emulation pauses before executing the instruction at a breakpoint.
+func (emulator *Emulator) SetBreakpoint(addr uint16, set bool) {
+	if set {
+		emulator.breakpoints[addr] = true
+	} else {
+		delete(emulator.breakpoints, addr)
+	}
+}
+
+// Breakpoint tells if there is a breakpoint on an address.
+func (emulator *Emulator) Breakpoint(addr uint16) bool {
+	return emulator.breakpoints[addr]
+}
+
+// SetMuted silences the buzzer, or plays it again.
+func (emulator *Emulator) SetMuted(muted bool) {
+	emulator.muted = muted
+}
+
+// Muted tells if the buzzer is silenced.
+func (emulator *Emulator) Muted() bool {
+	return emulator.muted
+}
+
+// SetKeymap sets the keys of the keyboard mapped to the keypad.
+func (emulator *Emulator) SetKeymap(keymap Keymap) {
+	emulator.keymap = keymap
+}
+
+// Keymap returns the keys of the keyboard mapped to the keypad.
+func (emulator *Emulator) Keymap() Keymap {
+	return emulator.keymap
+}
+
+// LoadKeymaps sets the keymap of the ROM from a configuration file, where the keymaps rebound are saved.
+func (emulator *Emulator) LoadKeymaps(filename string) error {
+	config, err := LoadKeymapConfig(filename)
+	if err != nil {
+		return err
+	}
+	keymap, err := config.Keymap(emulator.rom)
+	if err != nil {
+		return errors.Wrapf(err, "invalid keymap for %s in %s", emulator.rom.Name, filename)
+	}
+	emulator.keymap = keymap
+	emulator.keymaps = config
+	emulator.keymapConfig = filename
+	return nil
+}
+
+// Rebind sets the keymap rebound by the user, and saves it as the keymap of the ROM.
+func (emulator *Emulator) Rebind(keymap Keymap) {
+	emulator.keymap = keymap
+	if emulator.keymaps == nil {
+		return
+	}
+	emulator.keymaps.SetKeymap(emulator.rom, keymap)
+	emulator.report(emulator.keymaps.Save(emulator.keymapConfig))
+}
+
+// Reset restarts the program from the beginning.
+// A ROM too large for the memory is a fault, the program doesn't run.
+func (emulator *Emulator) Reset() {
+	emulator.display.Clear()
+	emulator.ram = NewRAM()
+	romErr := emulator.ram.LoadRom(emulator.rom)
+	emulator.ram.LoadFont(Font)
+	emulator.memory.ram = emulator.ram // the devices mapped stay
+	emulator.cpu = NewCPU()
+	emulator.keypad.reset()
+	emulator.wait = keyWait{}
+	emulator.frame = 0
+	emulator.current = nil
+	emulator.fault = nil
+	emulator.halt = nil
+	emulator.loops = make(map[uint16]loopState)
+	emulator.timers = emulator.activeTimers()
+	if romErr != nil { // the program can't run
+		emulator.fault = &Fault{Addr: ProgramLocation, Reason: romErr.Error()}
 	}
 }
 
-func (emulator *Emulator) Run() {
-	pixelgl.Run(func() {
-		// display
-		emulator.display.Init()
-		emulator.display.Clear()
-		emulator.display.window.SetTitle(emulator.rom.Name)
+// SetKey presses or releases a key of the keypad, from 0 to F.
+func (emulator *Emulator) SetKey(key int, pressed bool) {
+	emulator.keypad.Set(key, pressed)
+}
+
+// Keypad returns the state of the keys, which frontends feed with the presses and releases of the keys.
+func (emulator *Emulator) Keypad() *Keypad {
+	return emulator.keypad
+}
+
+// Display returns the display, with the settings of the frontends showing it.
+func (emulator *Emulator) Display() *Display {
+	return emulator.display
+}
+
+// RAM returns the memory of the program, as loaded by the last reset.
+func (emulator *Emulator) RAM() *RAM {
+	return emulator.ram
+}
+
+// ROM returns the program emulated.
+func (emulator *Emulator) ROM() *ROM {
+	return emulator.rom
+}
+
+// Frames returns the number of frames executed since the reset.
+func (emulator *Emulator) Frames() int {
+	return emulator.frame
+}
+
+// Current returns the last instruction executed, nil before the first one.
+func (emulator *Emulator) Current() Instruction {
+	return emulator.current
+}
+
+// Screen returns the pixels of the screen, 1 when on, row by row.
+func (emulator *Emulator) Screen() Screen {
+	return emulator.display.memory
+}
 
-		// memory
-		emulator.ram.LoadRom(emulator.rom)
-		emulator.ram.LoadFont(Font)
+// RunFrontend emulates the program until it ends or the user quits the frontend.
+// A fault of the program is not an error of the frontend, it is returned by Fault.
+func (emulator *Emulator) RunFrontend(frontend Frontend) (err error) {
+	emulator.Reset()
+	if err := frontend.Open(emulator); err != nil {
+		return err
+	}
+	defer emulator.audio.Close()
+	defer emulator.stopCapture()
+	defer func() {
+		if closeErr := frontend.Close(); err == nil {
+			err = closeErr
+		}
+	}()
 
-		for {
-			instruction := emulator.cpu.ReadInstruction(emulator)
-			if instruction == nil {
-				break
-			}
-			fmt.Println(instruction)
-			instruction.Execute()
-			emulator.cpu.UpdateTimers()
+	for emulator.Frame() && frontend.Update() {
+	}
+	return nil
+}
 
-			// slow down processor
-			time.Sleep(1400 * time.Microsecond)
+// Frame executes the instructions of one frame then ticks the timers, unless the emulation is paused.
+// It returns false when the program ends, as told by Halt, or faults.
+func (emulator *Emulator) Frame() bool {
+	if emulator.paused {
+		if emulator.advance == 0 {
+			return true
 		}
-	})
+		emulator.advance--
+	}
+	emulator.yield = false
+	for i := 0; i < emulator.speed && !emulator.yield; i++ {
+		if emulator.breakpoints[emulator.cpu.pc] && !emulator.hit {
+			emulator.hit = true
+			emulator.SetPaused(true)
+			return true
+		}
+		if !emulator.Step() {
+			return false
+		}
+	}
+	emulator.keypad.endFrame()
+	emulator.tick()
+	emulator.frame++
+	emulator.record()
+	if emulator.hooks.Frame != nil {
+		emulator.hooks.Frame(emulator.frame)
+	}
+	return true
+}
+
+// Step executes one instruction, without ticking the timers.
+// It returns false when the program ends, as told by Halt, or faults.
+func (emulator *Emulator) Step() bool {
+	if emulator.fault != nil || emulator.halt != nil {
+		return false
+	}
+	if pc := emulator.cpu.pc; int(pc)+1 >= RamSize {
+		emulator.fault = &Fault{Addr: pc, Reason: "program counter out of memory"}
+		return false
+	}
+	instruction := emulator.cpu.ReadInstruction(emulator)
+	if instruction == nil {
+		emulator.halt = &Halt{Reason: HaltEnd, Addr: emulator.cpu.pc}
+		return false
+	}
+	if emulator.trace != nil {
+		fmt.Fprintln(emulator.trace, instruction)
+	}
+	if emulator.hooks.BeforeInstruction != nil {
+		emulator.hooks.BeforeInstruction(instruction)
+	}
+	instruction.Execute()
+	if emulator.hooks.AfterInstruction != nil {
+		emulator.hooks.AfterInstruction(instruction)
+	}
+	emulator.checkTimers()
+	emulator.current = instruction
+	emulator.executed++
+	emulator.hit = false
+	return emulator.fault == nil && emulator.halt == nil
+}
+
+// tick updates the timers and plays the buzzer.
+func (emulator *Emulator) tick() {
+	buzzer := emulator.cpu.Buzzing()
+	emulator.cpu.UpdateTimers()
+	emulator.checkTimers()
+	if err := emulator.audio.Tick(buzzer && !emulator.muted); err != nil {
+		emulator.report(err)
+		emulator.audio = NullSink{}
+	}
+}
+
+// Screenshot saves the screen into a file named after the ROM and the frame.
+func (emulator *Emulator) Screenshot() {
+	filename := captureName(emulator.rom, emulator.frame, ".png")
+	emulator.report(emulator.display.memory.SavePNG(filename, emulator.display.Theme(), emulator.capture.Scale))
+}
+
+// ToggleRecording starts recording the screen, or stops and saves the recording into a file named after the ROM and the frame.
+func (emulator *Emulator) ToggleRecording() {
+	if emulator.recording == nil {
+		emulator.recording = NewRecorder(emulator.display.Theme(), emulator.capture.Scale)
+		return
+	}
+	filename := captureName(emulator.rom, emulator.frame, ".gif")
+	emulator.report(emulator.recording.Save(filename))
+	emulator.recording = nil
+}
+
+// record captures the frame that just ended.
+func (emulator *Emulator) record() {
+	c := &emulator.capture
+	screen := &emulator.display.memory
+	if c.Screenshot != "" && c.ScreenshotFrame == emulator.frame {
+		emulator.report(screen.SavePNG(c.Screenshot, emulator.display.Theme(), c.Scale))
+	}
+	if c.GIF != "" && emulator.frame >= c.GIFFrom && (c.GIFFrames == 0 || emulator.frame < c.GIFFrom+c.GIFFrames) {
+		if emulator.recorder == nil {
+			emulator.recorder = NewRecorder(emulator.display.Theme(), c.Scale)
+		}
+		emulator.recorder.Record(screen)
+		if emulator.recorder.Frames() == c.GIFFrames {
+			emulator.report(emulator.recorder.Save(c.GIF))
+			emulator.recorder = nil
+			c.GIF = ""
+		}
+	}
+	if emulator.recording != nil {
+		emulator.recording.Record(screen)
+	}
+}
+
+// stopCapture saves what is captured until the emulator stops.
+func (emulator *Emulator) stopCapture() {
+	c := &emulator.capture
+	if c.Screenshot != "" && c.ScreenshotFrame == 0 {
+		emulator.report(emulator.display.memory.SavePNG(c.Screenshot, emulator.display.Theme(), c.Scale))
+	}
+	if emulator.recorder != nil {
+		emulator.report(emulator.recorder.Save(c.GIF))
+	}
+	if emulator.recording != nil {
+		emulator.report(emulator.recording.Save(captureName(emulator.rom, emulator.frame, ".gif")))
+	}
+}
+
+// report prints the errors which don't stop the emulator.
+func (emulator *Emulator) report(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
 }
@@ -0,0 +1,40 @@
+package chip8
+
+import "fmt"
+
+// Fault is an error of the program which stops the emulation,
+// such as an access out of the memory, an overflow of the call stack or an unknown instruction.
+type Fault struct {
+	Addr   uint16 // address of the instruction
+	Opcode uint16 // value of the instruction
+	Reason string
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("%s at %03X (%04X)", f.Reason, f.Addr, f.Opcode)
+}
+
+// Fault returns the fault which stopped the program, or nil.
+func (emulator *Emulator) Fault() error {
+	if emulator.fault == nil {
+		return nil
+	}
+	return emulator.fault
+}
+
+// fault stops the emulation on an error of the program.
+func (b *BaseInstruction) fault(format string, args ...interface{}) {
+	b.emulator.fault = &Fault{Addr: b.addr, Opcode: b.val, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Unknown is a value which is not an instruction, executing it is a fault.
+type Unknown struct{ *BaseInstruction }
+
+// Execute the instruction.
+func (u *Unknown) Execute() {
+	u.fault("unknown instruction")
+}
+
+func (u *Unknown) String() string {
+	return fmt.Sprintf("%04X - %04X - ???", u.addr, u.val)
+}
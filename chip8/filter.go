@@ -0,0 +1,134 @@
+package chip8
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultBlendFrames   = 3
+	DefaultPhosphorDecay = 0.6
+)
+
+// Screen is the state of every pixel of the display, row after row.
+type Screen [DisplayWidth * DisplayHeight]byte
+
+// Brightness is the intensity of every pixel of the display, from 0 (off) to 1 (on).
+type Brightness [DisplayWidth * DisplayHeight]float64
+
+// DisplayFilter reduces the flickering caused by the sprites being erased and redrawn between frames.
+// Filters only change what is shown, never the memory of the display seen by the program.
+type DisplayFilter interface {
+	// Filter is called once per frame and computes the brightness of the pixels from the screen.
+	Filter(screen *Screen, brightness *Brightness)
+}
+
+// ParseFilter returns the filter described by spec:
+// "none", "or", "blend" or "blend:frames", "phosphor" or "phosphor:decay".
+func ParseFilter(spec string) (DisplayFilter, error) {
+	name, arg := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		name, arg = spec[:i], spec[i+1:]
+	}
+	switch name {
+	case "none":
+		if arg == "" {
+			return NoFilter{}, nil
+		}
+	case "or":
+		if arg == "" {
+			return &ORFilter{}, nil
+		}
+	case "blend":
+		frames := DefaultBlendFrames
+		if arg != "" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return nil, errors.Errorf("invalid number of frames to blend %q", arg)
+			}
+			frames = n
+		}
+		return NewBlendFilter(frames), nil
+	case "phosphor":
+		decay := DefaultPhosphorDecay
+		if arg != "" {
+			d, err := strconv.ParseFloat(arg, 64)
+			if err != nil || d < 0 || d >= 1 {
+				return nil, errors.Errorf("invalid phosphor decay %q", arg)
+			}
+			decay = d
+		}
+		return &PhosphorFilter{Decay: decay}, nil
+	}
+	return nil, errors.Errorf("unknown filter %q", spec)
+}
+
+// NoFilter shows the screen as it is.
+type NoFilter struct{}
+
+// Filter the screen.
+func (NoFilter) Filter(screen *Screen, brightness *Brightness) {
+	for i, p := range screen {
+		brightness[i] = float64(p)
+	}
+}
+
+// ORFilter lights the pixels that are on in the current frame or in the previous one.
+type ORFilter struct {
+	previous Screen
+}
+
+// Filter the screen.
+func (f *ORFilter) Filter(screen *Screen, brightness *Brightness) {
+	for i, p := range screen {
+		brightness[i] = float64(p | f.previous[i])
+	}
+	f.previous = *screen
+}
+
+// BlendFilter averages the last frames.
+type BlendFilter struct {
+	frames []Screen
+	next   int
+}
+
+func NewBlendFilter(frames int) *BlendFilter {
+	return &BlendFilter{frames: make([]Screen, frames)}
+}
+
+// Filter the screen.
+func (f *BlendFilter) Filter(screen *Screen, brightness *Brightness) {
+	f.frames[f.next] = *screen
+	f.next = (f.next + 1) % len(f.frames)
+	for i := range brightness {
+		sum := 0
+		for j := range f.frames {
+			sum += int(f.frames[j][i])
+		}
+		brightness[i] = float64(sum) / float64(len(f.frames))
+	}
+}
+
+// PhosphorFilter simulates the persistence of the phosphor of a CRT:
+// a pixel turned off fades out, losing the Decay part of its brightness every frame.
+type PhosphorFilter struct {
+	Decay float64
+	level Brightness
+}
+
+// Filter the screen.
+func (f *PhosphorFilter) Filter(screen *Screen, brightness *Brightness) {
+	for i, p := range screen {
+		if p == 1 {
+			f.level[i] = 1
+		} else {
+			f.level[i] *= 1 - f.Decay
+			if f.level[i] < 1.0/256 {
+				f.level[i] = 0
+			}
+		}
+	}
+	*brightness = f.level
+}
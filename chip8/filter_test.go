@@ -0,0 +1,42 @@
+package chip8_test
+
+import (
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilters(t *testing.T) {
+	var on, off chip8.Screen
+	on[0] = 1
+
+	filter := func(f chip8.DisplayFilter, screens ...*chip8.Screen) float64 {
+		var brightness chip8.Brightness
+		for _, screen := range screens {
+			f.Filter(screen, &brightness)
+		}
+		return brightness[0]
+	}
+
+	require.Equal(t, 0.0, filter(chip8.NoFilter{}, &on, &off))
+	require.Equal(t, 1.0, filter(&chip8.ORFilter{}, &on, &off))
+	require.Equal(t, 0.0, filter(&chip8.ORFilter{}, &on, &off, &off))
+	require.InDelta(t, 2.0/3, filter(chip8.NewBlendFilter(3), &on, &off, &on), 1e-9)
+	require.InDelta(t, 0.25, filter(&chip8.PhosphorFilter{Decay: 0.5}, &on, &off, &off), 1e-9)
+}
+
+func TestParseFilter(t *testing.T) {
+	filter, err := chip8.ParseFilter("blend:4")
+	require.Nil(t, err)
+	require.IsType(t, &chip8.BlendFilter{}, filter)
+
+	filter, err = chip8.ParseFilter("phosphor")
+	require.Nil(t, err)
+	require.Equal(t, chip8.DefaultPhosphorDecay, filter.(*chip8.PhosphorFilter).Decay)
+
+	for _, spec := range []string{"blur", "blend:0", "phosphor:1", "or:2"} {
+		_, err = chip8.ParseFilter(spec)
+		require.NotNil(t, err, spec)
+	}
+}
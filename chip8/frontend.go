@@ -0,0 +1,28 @@
+package chip8
+
+import "time"
+
+// Frontend shows the display of the emulator and feeds it the keys pressed by the user.
+type Frontend interface {
+	// Open is called before the first frame.
+	Open(emulator *Emulator) error
+	// Update is called at the end of every frame to show the display and update the keys.
+	// It paces the emulation and returns false when the user quits.
+	Update() bool
+	Close() error
+}
+
+// pacer runs the frontends without vertical sync at 60 frames per second.
+type pacer struct {
+	next time.Time // when the next frame starts
+}
+
+// wait waits for the next frame, without catching up after a stall.
+func (p *pacer) wait() {
+	p.next = p.next.Add(time.Second / TimerFrequency)
+	if wait := time.Until(p.next); wait > 0 {
+		time.Sleep(wait)
+	} else if wait < -time.Second/10 {
+		p.next = time.Now()
+	}
+}
@@ -0,0 +1,141 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HaltReason is the way a program signaled it is done.
+type HaltReason int
+
+const (
+	HaltEnd      HaltReason = iota // the instruction 0000
+	HaltSelfJump                   // 1nnn jumping to itself
+	HaltLoop                       // a loop jumping back without changing the state
+	HaltKeyWait                    // Fx0A waiting for a key while none is pressed
+)
+
+func (r HaltReason) String() string {
+	switch r {
+	case HaltEnd:
+		return "end of the program"
+	case HaltSelfJump:
+		return "jump to itself"
+	case HaltLoop:
+		return "loop without change"
+	case HaltKeyWait:
+		return "wait for a key"
+	}
+	return fmt.Sprintf("HaltReason(%d)", int(r))
+}
+
+// Halt is the end of a program, detected at the address of an instruction.
+type Halt struct {
+	Reason HaltReason
+	Addr   uint16
+}
+
+func (h *Halt) String() string {
+	return fmt.Sprintf("%s at %03X", h.Reason, h.Addr)
+}
+
+// HaltDetectors select the ways of signaling the end the emulation stops on, besides the instruction 0000.
+// As the test ROMs usually end on one of them, the harnesses can stop reliably when they are done.
+// The loops and the waits for a key are ended by the keys pressed, so they are meant for the runs without user.
+type HaltDetectors struct {
+	SelfJump bool // 1nnn jumps to itself
+	Loop     bool // a loop jumps back with the same registers, nothing written nor drawn, and the delay timer at 0
+	KeyWait  bool // Fx0A waits for a key while none is pressed
+}
+
+var haltDetectorNames = []string{"selfjump", "loop", "keywait"}
+
+// flags returns the fields of the detectors in the order of their names.
+func (d *HaltDetectors) flags() []*bool {
+	return []*bool{&d.SelfJump, &d.Loop, &d.KeyWait}
+}
+
+// ParseHaltDetectors returns the detectors named in a comma separated list, "all" or "none".
+func ParseHaltDetectors(spec string) (HaltDetectors, error) {
+	var detectors HaltDetectors
+	switch spec {
+	case "", "none":
+		return detectors, nil
+	case "all":
+		return HaltDetectors{SelfJump: true, Loop: true, KeyWait: true}, nil
+	}
+	flags := detectors.flags()
+next:
+	for _, name := range strings.Split(spec, ",") {
+		for i, n := range haltDetectorNames {
+			if n == strings.TrimSpace(name) {
+				*flags[i] = true
+				continue next
+			}
+		}
+		return HaltDetectors{}, errors.Errorf("unknown halt detector %q", name)
+	}
+	return detectors, nil
+}
+
+// String returns the names of the detectors enabled, separated by commas.
+func (d HaltDetectors) String() string {
+	var names []string
+	for i, flag := range d.flags() {
+		if *flag {
+			names = append(names, haltDetectorNames[i])
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// SetHaltDetectors sets the ways of signaling the end the emulation stops on.
+func (emulator *Emulator) SetHaltDetectors(detectors HaltDetectors) {
+	emulator.detectors = detectors
+	emulator.loops = make(map[uint16]loopState)
+}
+
+// HaltDetectors returns the ways of signaling the end the emulation stops on.
+func (emulator *Emulator) HaltDetectors() HaltDetectors {
+	return emulator.detectors
+}
+
+// Halt returns how the program signaled its end, or nil while it runs.
+func (emulator *Emulator) Halt() *Halt {
+	return emulator.halt
+}
+
+// loopState is the state of the machine when a loop jumps back, compared on the next jump.
+type loopState struct {
+	v       [RegSize]uint8
+	i       uint16
+	sp      byte
+	changes int64
+}
+
+// jumpBack detects the end of the program when a jump at an address goes back.
+func (emulator *Emulator) jumpBack(addr uint16) {
+	cpu := emulator.cpu
+	if emulator.detectors.SelfJump && cpu.pc == addr {
+		emulator.halt = &Halt{Reason: HaltSelfJump, Addr: addr}
+		return
+	}
+	if !emulator.detectors.Loop {
+		return
+	}
+	if cpu.dt != 0 {
+		delete(emulator.loops, addr) // the state changes with the timer
+		return
+	}
+	state := loopState{v: cpu.v, i: cpu.i, sp: cpu.sp, changes: emulator.changes}
+	if last, ok := emulator.loops[addr]; ok && last == state {
+		emulator.halt = &Halt{Reason: HaltLoop, Addr: addr}
+		return
+	}
+	emulator.loops[addr] = state
+}
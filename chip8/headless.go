@@ -0,0 +1,55 @@
+package chip8
+
+// Headless is the frontend running the emulation without showing it, as fast as possible,
+// with the keys pressed from a schedule.
+type Headless struct {
+	Frames int        // frames to run, 0 for until the program ends
+	Input  []KeyEvent // keys pressed and released, sorted by frame
+	// Halts are the ends of the program detected. The loops and the waits for a key
+	// are only detected once the last key of the input is pressed or released.
+	Halts HaltDetectors
+
+	emulator *Emulator
+	frames   int
+	next     int // next event of the input
+}
+
+func NewHeadless(frames int) *Headless {
+	return &Headless{Frames: frames}
+}
+
+// Open sets the halt detectors and presses the keys of the first frame.
+func (h *Headless) Open(emulator *Emulator) error {
+	h.emulator = emulator
+	h.frames = 0
+	h.next = 0
+	h.input()
+	return nil
+}
+
+// Update counts the frames run and presses the keys of the next frame.
+func (h *Headless) Update() bool {
+	h.frames++
+	h.input()
+	return h.Frames == 0 || h.frames < h.Frames
+}
+
+// input presses and releases the keys of the next frame.
+func (h *Headless) input() {
+	for ; h.next < len(h.Input) && h.Input[h.next].Frame <= h.frames+1; h.next++ {
+		event := h.Input[h.next]
+		h.emulator.SetKey(event.Key, event.Pressed)
+	}
+	detectors := h.Halts
+	if h.next < len(h.Input) {
+		detectors.Loop, detectors.KeyWait = false, false // until the program has all its input
+	}
+	if detectors != h.emulator.HaltDetectors() {
+		h.emulator.SetHaltDetectors(detectors)
+	}
+}
+
+// Close does nothing.
+func (h *Headless) Close() error {
+	return nil
+}
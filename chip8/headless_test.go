@@ -0,0 +1,103 @@
+package chip8_test
+
+import (
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInput(t *testing.T) {
+	events, err := chip8.ParseInput("30:A+ 10:5 # tap 5\n40:a-,")
+	require.NoError(t, err)
+	require.Equal(t, []chip8.KeyEvent{
+		{Frame: 10, Key: 5, Pressed: true},
+		{Frame: 11, Key: 5, Pressed: false},
+		{Frame: 30, Key: 0xA, Pressed: true},
+		{Frame: 40, Key: 0xA, Pressed: false},
+	}, events)
+
+	for _, input := range []string{"5", "0:1", "1:G", "1:10", "x:1+"} {
+		_, err := chip8.ParseInput(input)
+		require.Error(t, err, input)
+	}
+}
+
+func TestHeadlessInput(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0xF0, 0x0A, // V0 = key pressed then released
+		0x00, 0x00, // end
+	}}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	headless := chip8.NewHeadless(100)
+	headless.Input = []chip8.KeyEvent{{Frame: 5, Key: 7, Pressed: true}, {Frame: 8, Key: 7, Pressed: false}}
+	require.NoError(t, emulator.RunFrontend(headless))
+	require.NoError(t, emulator.Fault())
+	require.Equal(t, uint8(7), emulator.State().V[0])
+	require.Equal(t, 7, emulator.State().Frame) // the program ends during the frame of the release
+}
+
+func TestFault(t *testing.T) {
+	for _, test := range []struct {
+		data  []byte
+		fault chip8.Fault
+	}{
+		{[]byte{0x22, 0x00}, chip8.Fault{Addr: 0x200, Opcode: 0x2200, Reason: "call stack overflow"}},
+		{[]byte{0x00, 0xEE}, chip8.Fault{Addr: 0x200, Opcode: 0x00EE, Reason: "return with an empty call stack"}},
+		{[]byte{0xAF, 0xFF, 0xD0, 0x02}, chip8.Fault{Addr: 0x202, Opcode: 0xD002, Reason: "sprite at FFF out of memory"}},
+		{[]byte{0xF0, 0xFF}, chip8.Fault{Addr: 0x200, Opcode: 0xF0FF, Reason: "unknown instruction"}},
+		{[]byte{0x1F, 0xFF}, chip8.Fault{Addr: 0xFFF, Reason: "program counter out of memory"}},
+	} {
+		emulator := chip8.NewEmulator(&chip8.ROM{Name: "test", Data: test.data})
+		emulator.SetTrace(nil)
+		emulator.Reset()
+		for emulator.Frame() {
+		}
+		require.Equal(t, &test.fault, emulator.Fault())
+		require.False(t, emulator.Step())
+
+		emulator.Reset()
+		require.NoError(t, emulator.Fault())
+	}
+}
+
+func TestHalt(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		data  []byte
+		input []chip8.KeyEvent
+		halt  chip8.Halt
+	}{
+		{"end", []byte{0x60, 0x01}, nil, chip8.Halt{Reason: chip8.HaltEnd, Addr: 0x202}},
+		{"self jump", []byte{0x60, 0x01, 0x12, 0x02}, nil, chip8.Halt{Reason: chip8.HaltSelfJump, Addr: 0x202}},
+		{"loop", []byte{
+			0x60, 0x01, // V0 = 1
+			0x30, 0x00, // skip if V0 == 0
+			0x12, 0x02, // jump back
+		}, nil, chip8.Halt{Reason: chip8.HaltLoop, Addr: 0x204}},
+		{"timer", []byte{
+			0x60, 0x05, // V0 = 5
+			0xF0, 0x15, // DT = V0
+			0xF1, 0x07, // V1 = DT
+			0x31, 0x00, // skip if V1 == 0
+			0x12, 0x04, // jump back
+			0x12, 0x0A, // jump to itself
+		}, nil, chip8.Halt{Reason: chip8.HaltSelfJump, Addr: 0x20A}},
+		{"key wait", []byte{0xF0, 0x0A}, nil, chip8.Halt{Reason: chip8.HaltKeyWait, Addr: 0x200}},
+		{"key", []byte{
+			0xF0, 0x0A, // V0 = key
+			0xF0, 0x0A, // V0 = key
+		}, []chip8.KeyEvent{{Frame: 30, Key: 1, Pressed: true}, {Frame: 31, Key: 1, Pressed: false}},
+			chip8.Halt{Reason: chip8.HaltKeyWait, Addr: 0x202}},
+	} {
+		emulator := chip8.NewEmulator(&chip8.ROM{Name: "test", Data: test.data})
+		emulator.SetTrace(nil)
+		headless := chip8.NewHeadless(1000)
+		headless.Input = test.input
+		headless.Halts = chip8.HaltDetectors{SelfJump: true, Loop: true, KeyWait: true}
+		require.NoError(t, emulator.RunFrontend(headless), test.name)
+		require.Equal(t, &test.halt, emulator.Halt(), test.name)
+		require.True(t, emulator.State().Frame < 100, test.name)
+	}
+}
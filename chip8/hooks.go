@@ -0,0 +1,95 @@
+package chip8
+
+// Hooks are callbacks of the emulation, to build tracers, coverage, cheats or achievements outside the emulator.
+// They are called in the goroutine of the emulation, each being nil when unused.
+type Hooks struct {
+	BeforeInstruction func(instruction Instruction) // before the instruction is executed
+	AfterInstruction  func(instruction Instruction) // after the instruction is executed
+	Read              func(access MemoryAccess)     // the program read a byte of the memory, but for the instructions
+	Write             func(access MemoryAccess)     // the program wrote a byte of the memory
+	Draw              func(draw DrawEvent)          // Dxyn drew a sprite
+	Timer             func(timer TimerEvent)        // the delay timer or the buzzer started or stopped
+	KeyWait           func(wait KeyWaitEvent)       // Fx0A started or ended waiting for a key
+	Frame             func(frame int)               // a frame ended, counted from 1
+}
+
+// MemoryAccess is a read or a write of a byte of the memory. Old and New are the same for a read.
+// Old is 0 for a write to a device which can't be read without side effects, such as IO.
+type MemoryAccess struct {
+	Addr     uint16
+	Old, New byte
+	PC       uint16 // address of the instruction
+}
+
+// DrawEvent is a sprite drawn by Dxyn.
+type DrawEvent struct {
+	X, Y      uint8  // coordinates, the values of Vx and Vy
+	Height    int    // number of rows of the sprite
+	Sprite    uint16 // address of the sprite, the value of I
+	Collision bool   // a pixel was erased, VF is set to 1
+	PC        uint16 // address of the instruction
+}
+
+// TimerEvent is a timer becoming active or inactive: RegDT for the delay timer, RegST for the buzzer.
+type TimerEvent struct {
+	Timer  Register
+	Active bool
+}
+
+// KeyWaitEvent is the start of a wait of Fx0A, or its end with the key pressed then released.
+type KeyWaitEvent struct {
+	X       int  // register receiving the key
+	Waiting bool // the wait started, or ended
+	Key     int  // key received, -1 while waiting
+	PC      uint16
+}
+
+// SetHooks sets the callbacks of the emulation.
+func (emulator *Emulator) SetHooks(hooks Hooks) {
+	emulator.hooks = hooks
+	emulator.timers = emulator.activeTimers()
+}
+
+// Hooks returns the callbacks of the emulation, to chain new ones with them.
+func (emulator *Emulator) Hooks() Hooks {
+	return emulator.hooks
+}
+
+// read returns a byte of the memory read by an instruction.
+func (b *BaseInstruction) read(addr uint16) byte {
+	value := b.emulator.bus.Read(addr)
+	if b.emulator.hooks.Read != nil {
+		b.emulator.hooks.Read(MemoryAccess{Addr: addr, Old: value, New: value, PC: b.addr})
+	}
+	return value
+}
+
+// write stores a byte into the memory written by an instruction.
+func (b *BaseInstruction) write(addr uint16, value byte) {
+	if b.emulator.hooks.Write == nil {
+		b.emulator.bus.Write(addr, value)
+		return
+	}
+	old := peek(b.emulator.bus, addr) // the hook doesn't change what the program sees
+	b.emulator.bus.Write(addr, value)
+	b.emulator.hooks.Write(MemoryAccess{Addr: addr, Old: old, New: value, PC: b.addr})
+}
+
+// activeTimers returns whether the delay timer and the sound timer are active.
+func (emulator *Emulator) activeTimers() [2]bool {
+	return [2]bool{emulator.cpu.dt > 0, emulator.cpu.st > 0}
+}
+
+// checkTimers calls the timer hook for the timers started or stopped since the last check.
+func (emulator *Emulator) checkTimers() {
+	if emulator.hooks.Timer == nil {
+		return
+	}
+	timers := emulator.activeTimers()
+	for i, timer := range []Register{RegDT, RegST} {
+		if timers[i] != emulator.timers[i] {
+			emulator.hooks.Timer(TimerEvent{Timer: timer, Active: timers[i]})
+		}
+	}
+	emulator.timers = timers
+}
@@ -0,0 +1,85 @@
+package chip8_test
+
+import (
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0x60, 0x03, // V0 = 3
+		0xF0, 0x15, // DT = V0
+		0xA3, 0x00, // I = 0x300
+		0xF0, 0x33, // BCD of V0 at I
+		0xD1, 0x13, // draw 3 rows at (V1, V1)
+		0xF2, 0x0A, // V2 = key
+		0x12, 0x0C, // jump to itself
+	}}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+
+	var before, after []uint16
+	var reads, writes []chip8.MemoryAccess
+	var draws []chip8.DrawEvent
+	var timers []chip8.TimerEvent
+	var waits []chip8.KeyWaitEvent
+	frames := 0
+	emulator.SetHooks(chip8.Hooks{
+		BeforeInstruction: func(instruction chip8.Instruction) { before = append(before, instruction.Addr()) },
+		AfterInstruction:  func(instruction chip8.Instruction) { after = append(after, instruction.Opcode()) },
+		Read:              func(access chip8.MemoryAccess) { reads = append(reads, access) },
+		Write:             func(access chip8.MemoryAccess) { writes = append(writes, access) },
+		Draw:              func(draw chip8.DrawEvent) { draws = append(draws, draw) },
+		Timer:             func(timer chip8.TimerEvent) { timers = append(timers, timer) },
+		KeyWait:           func(wait chip8.KeyWaitEvent) { waits = append(waits, wait) },
+		Frame:             func(frame int) { frames = frame },
+	})
+	headless := chip8.NewHeadless(10)
+	headless.Input = []chip8.KeyEvent{{Frame: 5, Key: 4, Pressed: true}, {Frame: 6, Key: 4, Pressed: false}}
+	require.NoError(t, emulator.RunFrontend(headless))
+
+	require.Equal(t, []uint16{0x200, 0x202, 0x204, 0x206, 0x208, 0x20A}, before[:6])
+	require.Equal(t, []uint16{0x6003, 0xF015, 0xA300, 0xF033, 0xD113, 0xF20A}, after[:6])
+	require.Equal(t, []chip8.MemoryAccess{
+		{Addr: 0x300, Old: 0, New: 0, PC: 0x206},
+		{Addr: 0x301, Old: 0, New: 0, PC: 0x206},
+		{Addr: 0x302, Old: 0, New: 3, PC: 0x206},
+	}, writes)
+	require.Equal(t, []chip8.MemoryAccess{
+		{Addr: 0x300, PC: 0x208},
+		{Addr: 0x301, PC: 0x208},
+		{Addr: 0x302, Old: 3, New: 3, PC: 0x208},
+	}, reads)
+	require.Equal(t, []chip8.DrawEvent{{X: 0, Y: 0, Height: 3, Sprite: 0x300, PC: 0x208}}, draws)
+	require.Equal(t, []chip8.TimerEvent{{Timer: chip8.RegDT, Active: true}, {Timer: chip8.RegDT, Active: false}}, timers)
+	require.Equal(t, []chip8.KeyWaitEvent{{X: 2, Waiting: true, Key: -1, PC: 0x20A}, {X: 2, Key: 4, PC: 0x20A}}, waits)
+	require.Equal(t, 10, frames)
+}
+
+func TestWriteHookIO(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0x60, 0x42, // V0 = 0x42
+		0xAF, 0xF0, // I = 0xFF0
+		0xF0, 0x55, // store V0 at I, in the device
+		0x12, 0x06, // jump to itself
+	}}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	reads := 0
+	require.NoError(t, emulator.MemoryBus().Map(0xFF0, 0x10, chip8.IO{
+		OnRead:  func(offset uint16) byte { reads++; return 0xFF },
+		OnWrite: func(offset uint16, b byte) {},
+	}))
+	counter := &chip8.Counter{Bus: emulator.Bus()}
+	emulator.SetBus(counter)
+	var writes []chip8.MemoryAccess
+	emulator.SetHooks(chip8.Hooks{Write: func(access chip8.MemoryAccess) { writes = append(writes, access) }})
+
+	require.True(t, emulator.Frame())
+	require.Equal(t, []chip8.MemoryAccess{{Addr: 0xFF0, New: 0x42, PC: 0x204}}, writes)
+	require.Equal(t, 0, reads)
+	require.Equal(t, int64(0), counter.Reads[0xFF0])
+}
@@ -0,0 +1,82 @@
+package chip8
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// MonochromePalette is the palette of the screen as an image, black when off and white when on.
+var MonochromePalette = color.Palette{color.Black, color.White}
+
+// ColorModel returns MonochromePalette: the screen is an image.PalettedImage of a pixel per pixel of the display.
+func (screen *Screen) ColorModel() color.Model {
+	return MonochromePalette
+}
+
+// Bounds returns the size of the display.
+func (screen *Screen) Bounds() image.Rectangle {
+	return image.Rect(0, 0, DisplayWidth, DisplayHeight)
+}
+
+// At returns the color of a pixel in MonochromePalette.
+func (screen *Screen) At(x, y int) color.Color {
+	return MonochromePalette[screen.ColorIndexAt(x, y)]
+}
+
+// ColorIndexAt returns 1 when a pixel is on, 0 when it is off or out of the screen.
+func (screen *Screen) ColorIndexAt(x, y int) uint8 {
+	if x < 0 || x >= DisplayWidth || y < 0 || y >= DisplayHeight || screen[y*DisplayWidth+x] == 0 {
+		return 0
+	}
+	return 1
+}
+
+// Image draws the screen with a palette of the colors of the pixels off then on,
+// each pixel being a square of the given size. MonochromePalette is used without palette.
+func (screen *Screen) Image(palette color.Palette, scale int) *image.Paletted {
+	if len(palette) < 2 {
+		palette = MonochromePalette
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	img := image.NewPaletted(image.Rect(0, 0, DisplayWidth*scale, DisplayHeight*scale), palette)
+	for y := 0; y < DisplayHeight*scale; y++ {
+		for x := 0; x < DisplayWidth*scale; x++ {
+			img.Pix[y*img.Stride+x] = screen.ColorIndexAt(x/scale, y/scale)
+		}
+	}
+	return img
+}
+
+// Paletted draws the screen with the colors of the theme, each pixel being a square of the given size.
+func (screen *Screen) Paletted(theme Theme, scale int) *image.Paletted {
+	return screen.Image(theme.Palette(), scale)
+}
+
+// Text draws the screen as text, a line per row, with a string for each pixel on and another for each pixel off.
+func (screen *Screen) Text(on, off string) string {
+	var b strings.Builder
+	for y := 0; y < DisplayHeight; y++ {
+		for x := 0; x < DisplayWidth; x++ {
+			if screen.ColorIndexAt(x, y) != 0 {
+				b.WriteString(on)
+			} else {
+				b.WriteString(off)
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ASCII draws the screen as text, with # for the pixels on and . for those off.
+func (screen *Screen) ASCII() string {
+	return screen.Text("#", ".")
+}
+
+// Image draws the screen with the colors of the theme of the display, each pixel being a square of the given size.
+func (emulator *Emulator) Image(scale int) *image.Paletted {
+	return emulator.display.memory.Image(emulator.display.Theme().Palette(), scale)
+}
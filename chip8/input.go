@@ -0,0 +1,57 @@
+package chip8
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// KeyEvent presses or releases a key of the keypad before a frame, counted from 1.
+type KeyEvent struct {
+	Frame   int
+	Key     int
+	Pressed bool
+}
+
+// ParseInput parses a schedule of key events separated by commas, spaces or lines, # starting a comment till the end of the line.
+// An event is written FRAME:KEY+ to press a key before a frame, FRAME:KEY- to release it,
+// or FRAME:KEY to tap it, pressed during the frame then released. The keys are 0 to F.
+// The events are returned sorted by frame.
+func ParseInput(input string) ([]KeyEvent, error) {
+	var events []KeyEvent
+	for _, line := range strings.Split(input, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\r' }) {
+			parts := strings.Split(field, ":")
+			if len(parts) != 2 {
+				return nil, errors.Errorf("invalid key event %q, expected FRAME:KEY", field)
+			}
+			frame, err := strconv.Atoi(parts[0])
+			if err != nil || frame <= 0 {
+				return nil, errors.Errorf("invalid frame in key event %q", field)
+			}
+			key, action := parts[1], ""
+			if strings.HasSuffix(key, "+") || strings.HasSuffix(key, "-") {
+				key, action = key[:len(key)-1], key[len(key)-1:]
+			}
+			k, err := strconv.ParseUint(key, 16, 8)
+			if err != nil || k >= KeyboardSize {
+				return nil, errors.Errorf("invalid key in key event %q, expected 0 to F", field)
+			}
+			switch action {
+			case "+":
+				events = append(events, KeyEvent{frame, int(k), true})
+			case "-":
+				events = append(events, KeyEvent{frame, int(k), false})
+			default:
+				events = append(events, KeyEvent{frame, int(k), true}, KeyEvent{frame + 1, int(k), false})
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Frame < events[j].Frame })
+	return events, nil
+}
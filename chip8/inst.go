@@ -2,11 +2,12 @@ package chip8
 
 import (
 	"fmt"
-	"math/rand"
 )
 
 type Instruction interface {
 	Execute()
+	Addr() uint16   // address of the instruction
+	Opcode() uint16 // value of the instruction
 	fmt.Stringer
 }
 
@@ -20,6 +21,16 @@ type BaseInstruction struct {
 func (b *BaseInstruction) Execute() {
 }
 
+// Addr returns the address of the instruction.
+func (b *BaseInstruction) Addr() uint16 {
+	return b.addr
+}
+
+// Opcode returns the value of the instruction.
+func (b *BaseInstruction) Opcode() uint16 {
+	return b.val
+}
+
 func (b *BaseInstruction) String() string {
 	return fmt.Sprintf("%04X - %04X", b.addr, b.val)
 }
@@ -31,6 +42,7 @@ type Clear struct{ *BaseInstruction }
 // Execute the instruction.
 func (c *Clear) Execute() {
 	c.emulator.display.Clear()
+	c.emulator.changes++
 }
 
 func (c *Clear) String() string {
@@ -44,6 +56,14 @@ type Return struct{ *BaseInstruction }
 
 // Execute the instruction.
 func (r *Return) Execute() {
+	if r.emulator.cpu.sp == 0 {
+		r.fault("return with an empty call stack")
+		return
+	}
+	if int(r.emulator.cpu.sp) >= StackSize {
+		r.fault("call stack pointer %d out of the stack", r.emulator.cpu.sp)
+		return
+	}
 	r.emulator.cpu.pc = r.emulator.cpu.stack[r.emulator.cpu.sp] // retrieve the program counter from the call stack
 	r.emulator.cpu.sp--                                         // decrement the stack
 }
@@ -61,6 +81,9 @@ type Jump struct{ *BaseInstruction }
 func (j *Jump) Execute() {
 	nnn := j.val & 0xFFF
 	j.emulator.cpu.pc = nnn
+	if nnn <= j.addr {
+		j.emulator.jumpBack(j.addr)
+	}
 }
 
 func (j *Jump) String() string {
@@ -77,6 +100,10 @@ type Call struct{ *BaseInstruction }
 // Execute the instruction.
 func (c *Call) Execute() {
 	nnn := c.val & 0xFFF
+	if int(c.emulator.cpu.sp) >= StackSize-1 {
+		c.fault("call stack overflow")
+		return
+	}
 	c.emulator.cpu.sp++                                         // increment the stack
 	c.emulator.cpu.stack[c.emulator.cpu.sp] = c.emulator.cpu.pc // store the program counter on the call stack
 	c.emulator.cpu.pc = nnn                                     // set the program counter to the call address
@@ -213,6 +240,9 @@ func (o *OR) Execute() {
 	x := (o.val >> 8) & 0xF
 	y := (o.val >> 4) & 0xF
 	o.emulator.cpu.v[x] |= o.emulator.cpu.v[y] // bitwise OR
+	if o.emulator.quirks.ResetVF {
+		o.emulator.cpu.v[0xF] = 0
+	}
 }
 
 func (o *OR) String() string {
@@ -233,6 +263,9 @@ func (a *AND) Execute() {
 	x := (a.val >> 8) & 0xF
 	y := (a.val >> 4) & 0xF
 	a.emulator.cpu.v[x] &= a.emulator.cpu.v[y] // bitwise AND
+	if a.emulator.quirks.ResetVF {
+		a.emulator.cpu.v[0xF] = 0
+	}
 }
 
 func (a *AND) String() string {
@@ -253,6 +286,9 @@ func (r *XOR) Execute() {
 	x := (r.val >> 8) & 0xF
 	y := (r.val >> 4) & 0xF
 	r.emulator.cpu.v[x] ^= r.emulator.cpu.v[y] // bitwise XOR
+	if r.emulator.quirks.ResetVF {
+		r.emulator.cpu.v[0xF] = 0
+	}
 }
 
 func (r *XOR) String() string {
@@ -323,6 +359,9 @@ type SHR struct{ *BaseInstruction }
 // Execute the instruction.
 func (s *SHR) Execute() {
 	x := (s.val >> 8) & 0xF
+	if s.emulator.quirks.ShiftVy {
+		s.emulator.cpu.v[x] = s.emulator.cpu.v[(s.val>>4)&0xF]
+	}
 
 	s.emulator.cpu.v[0xF] = 0
 	if s.emulator.cpu.v[x]&1 == 1 {
@@ -372,6 +411,9 @@ type SHL struct{ *BaseInstruction }
 // Execute the instruction.
 func (s *SHL) Execute() {
 	x := (s.val >> 8) & 0xF
+	if s.emulator.quirks.ShiftVy {
+		s.emulator.cpu.v[x] = s.emulator.cpu.v[(s.val>>4)&0xF]
+	}
 
 	s.emulator.cpu.v[0xF] = 0
 	if (s.emulator.cpu.v[x]>>3)&1 == 1 {
@@ -425,13 +467,16 @@ func (l *LoadI) String() string {
 
 // JumpV0 jumps to location nnn + V0.
 // Bnnn - JP V0, addr
-// The program counter is set to nnn plus the value of V0.
+// The program counter is set to nnn plus the value of V0, or of Vx with the JumpVx quirk.
 type JumpV0 struct{ *BaseInstruction }
 
 // Execute the instruction.
 func (j *JumpV0) Execute() {
-	nnn := (j.val & 0xFFF) + uint16(j.emulator.cpu.v[0])
-	j.emulator.cpu.pc = nnn
+	v := j.emulator.cpu.v[0]
+	if j.emulator.quirks.JumpVx {
+		v = j.emulator.cpu.v[(j.val>>8)&0xF]
+	}
+	j.emulator.cpu.pc = (j.val & 0xFFF) + uint16(v)
 }
 
 func (j *JumpV0) String() string {
@@ -449,7 +494,8 @@ type RND struct{ *BaseInstruction }
 func (r *RND) Execute() {
 	x := (r.val >> 8) & 0xF
 	kk := r.val & 0xFF
-	r.emulator.cpu.v[x] = uint8(uint16(rand.Intn(255)) & kk) // bitwise AND
+	r.emulator.cpu.v[x] = uint8(uint16(r.emulator.rand.Intn(255)) & kk) // bitwise AND
+	r.emulator.changes++
 }
 
 func (r *RND) String() string {
@@ -473,15 +519,24 @@ func (d *Draw) Execute() {
 	x := d.emulator.cpu.v[(d.val>>8)&0xF]
 	y := d.emulator.cpu.v[(d.val>>4)&0xF]
 	height := d.val & 0xF
+	if int(d.emulator.cpu.i)+int(height) > RamSize {
+		d.fault("sprite at %03X out of memory", d.emulator.cpu.i)
+		return
+	}
 
 	d.emulator.cpu.v[0xF] = 0
 	for yline := uint16(0); yline < height; yline++ {
-		pixel := d.emulator.ram.data[d.emulator.cpu.i+yline]
+		pixel := d.read(d.emulator.cpu.i + yline)
 		for xline := uint16(0); xline < 8; xline++ {
 			if (pixel & (0x80 >> xline)) != 0 {
+				x := uint16(x)%DisplayWidth + xline
+				y := uint16(y)%DisplayHeight + yline
+				if d.emulator.quirks.Clip && (x >= DisplayWidth || y >= DisplayHeight) {
+					continue
+				}
 				// handle wrapping of screen
-				x := (uint16(x) + xline) % DisplayWidth
-				y := (uint16(y) + yline) % DisplayHeight
+				x %= DisplayWidth
+				y %= DisplayHeight
 				index := x + (y * 64)
 
 				// check collision
@@ -493,6 +548,11 @@ func (d *Draw) Execute() {
 			}
 		}
 	}
+	d.emulator.display.dirty = true
+	d.emulator.changes++
+	if d.emulator.hooks.Draw != nil {
+		d.emulator.hooks.Draw(DrawEvent{X: x, Y: y, Height: int(height), Sprite: d.emulator.cpu.i, Collision: d.emulator.cpu.v[0xF] == 1, PC: d.addr})
+	}
 }
 
 func (d *Draw) String() string {
@@ -511,7 +571,7 @@ type SkipKey struct{ *BaseInstruction }
 func (s *SkipKey) Execute() {
 	x := (s.val >> 8) & 0xF
 	vx := s.emulator.cpu.v[x]
-	if s.emulator.display.window.Pressed(Keys[vx]) {
+	if s.emulator.keypad.Down(int(vx & 0xF)) {
 		s.emulator.cpu.pc += InstructionSize // skip one instruction
 	}
 }
@@ -530,7 +590,7 @@ type SkipNotKey struct{ *BaseInstruction }
 func (s *SkipNotKey) Execute() {
 	x := (s.val >> 8) & 0xF
 	vx := s.emulator.cpu.v[x]
-	if !s.emulator.display.window.Pressed(Keys[vx]) {
+	if !s.emulator.keypad.Down(int(vx & 0xF)) {
 		s.emulator.cpu.pc += InstructionSize // skip one instruction
 	}
 }
@@ -559,19 +619,43 @@ func (g *GetDelayTimer) String() string {
 // WaitKey wait for a key press, store the value of the key in Vx.
 // Fx0A - LD Vx, K
 // All execution stops until a key is pressed, then the value of that key is stored in Vx.
+// As on the COSMAC VIP, the key must be pressed then released, the keys held before the wait are ignored.
 type WaitKey struct{ *BaseInstruction }
 
+// keyWait is the state of the wait of Fx0A.
+type keyWait struct {
+	active bool
+	key    int // key pressed during the wait, or -1
+}
+
 // Execute the instruction.
 func (w *WaitKey) Execute() {
 	x := (w.val >> 8) & 0xF
-	for {
-		for i, key := range Keys {
-			if w.emulator.display.window.Pressed(key) {
-				w.emulator.cpu.v[x] = uint8(i)
-				return
-			}
+	wait := &w.emulator.wait
+	keypad := w.emulator.keypad
+	if !wait.active {
+		*wait = keyWait{active: true, key: -1}
+		keypad.clearEdges()
+		if w.emulator.hooks.KeyWait != nil {
+			w.emulator.hooks.KeyWait(KeyWaitEvent{X: int(x), Waiting: true, Key: -1, PC: w.addr})
 		}
 	}
+	if wait.key < 0 {
+		wait.key = keypad.takePress()
+	}
+	if wait.key < 0 && w.emulator.detectors.KeyWait {
+		w.emulator.halt = &Halt{Reason: HaltKeyWait, Addr: w.addr}
+	}
+	if wait.key >= 0 && keypad.takeRelease(wait.key) {
+		w.emulator.cpu.v[x] = uint8(wait.key)
+		if w.emulator.hooks.KeyWait != nil {
+			w.emulator.hooks.KeyWait(KeyWaitEvent{X: int(x), Key: wait.key, PC: w.addr})
+		}
+		*wait = keyWait{}
+		return
+	}
+	w.emulator.cpu.pc -= InstructionSize // execute the instruction again until a key is released
+	w.emulator.yield = true              // on the next frame, instead of spinning
 }
 
 func (w *WaitKey) String() string {
@@ -655,9 +739,14 @@ func (s *StoreBCD) Execute() {
 	x := (s.val >> 8) & 0xF
 	vx := s.emulator.cpu.v[x]
 	i := s.emulator.cpu.i
-	s.emulator.ram.data[i] = byte(vx / 100)
-	s.emulator.ram.data[i+1] = byte((vx / 10) % 10)
-	s.emulator.ram.data[i+2] = byte((vx % 100) % 10)
+	if int(i)+2 >= RamSize {
+		s.fault("write at %03X out of memory", i)
+		return
+	}
+	s.write(i, byte(vx/100))
+	s.write(i+1, byte((vx/10)%10))
+	s.write(i+2, byte((vx%100)%10))
+	s.emulator.changes++
 }
 
 func (s *StoreBCD) String() string {
@@ -673,8 +762,16 @@ type WriteMemory struct{ *BaseInstruction }
 // Execute the instruction.
 func (w *WriteMemory) Execute() {
 	x := (w.val >> 8) & 0xF
+	if int(w.emulator.cpu.i)+int(x) >= RamSize {
+		w.fault("write at %03X out of memory", w.emulator.cpu.i)
+		return
+	}
 	for i := uint16(0); i <= x; i++ {
-		w.emulator.ram.data[w.emulator.cpu.i+i] = byte(w.emulator.cpu.v[i])
+		w.write(w.emulator.cpu.i+i, byte(w.emulator.cpu.v[i]))
+	}
+	w.emulator.changes++
+	if w.emulator.quirks.IncrementI {
+		w.emulator.cpu.i += x + 1
 	}
 }
 
@@ -691,8 +788,15 @@ type ReadMemory struct{ *BaseInstruction }
 // Execute the instruction.
 func (l *ReadMemory) Execute() {
 	x := (l.val >> 8) & 0xF
+	if int(l.emulator.cpu.i)+int(x) >= RamSize {
+		l.fault("read at %03X out of memory", l.emulator.cpu.i)
+		return
+	}
 	for i := uint16(0); i <= x; i++ {
-		l.emulator.cpu.v[i] = l.emulator.ram.data[l.emulator.cpu.i+i]
+		l.emulator.cpu.v[i] = l.read(l.emulator.cpu.i + i)
+	}
+	if l.emulator.quirks.IncrementI {
+		l.emulator.cpu.i += x + 1
 	}
 }
 
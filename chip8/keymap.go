@@ -0,0 +1,220 @@
+package chip8
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Keymap maps the keys of the keypad, from 0 to F, to the keys of the keyboard.
+// The keys of the keyboard are named after what is printed on them, such as "Q" or ";",
+// the keys of the number row after their digits whatever the layout prints on them,
+// and the keys of the numeric keypad are named "KP0" to "KP9", "KP/", "KP*", "KP-", "KP+", "KP." and "KPEnter".
+type Keymap struct {
+	Name string
+	Keys [KeyboardSize]string
+}
+
+// Keymaps are the builtin keymaps, the first one being the default.
+// They lay the keypad out on the keyboard as on the COSMAC VIP:
+//
+//	1 2 3 C
+//	4 5 6 D
+//	7 8 9 E
+//	A 0 B F
+var Keymaps = []Keymap{
+	{"qwerty", [KeyboardSize]string{"X", "1", "2", "3", "Q", "W", "E", "A", "S", "D", "Z", "C", "4", "R", "F", "V"}},
+	{"azerty", [KeyboardSize]string{"X", "1", "2", "3", "A", "Z", "E", "Q", "S", "D", "W", "C", "4", "R", "F", "V"}},
+	{"dvorak", [KeyboardSize]string{"Q", "1", "2", "3", "'", ",", ".", "A", "O", "E", ";", "J", "4", "P", "U", "K"}},
+	{"numpad", [KeyboardSize]string{"KP0", "KP7", "KP8", "KP9", "KP4", "KP5", "KP6", "KP1", "KP2", "KP3", "KP.", "KPEnter", "KP/", "KP*", "KP-", "KP+"}},
+}
+
+// KeypadLayout are the keys of the keypad row by row.
+var KeypadLayout = [KeyboardSize]int{0x1, 0x2, 0x3, 0xC, 0x4, 0x5, 0x6, 0xD, 0x7, 0x8, 0x9, 0xE, 0xA, 0x0, 0xB, 0xF}
+
+// ParseKeymap returns the builtin keymap with the given name,
+// or a custom keymap written as the 16 keys of the keypad from 0 to F separated by spaces.
+func ParseKeymap(spec string) (Keymap, error) {
+	for _, keymap := range Keymaps {
+		if keymap.Name == spec {
+			return keymap, nil
+		}
+	}
+
+	keys := strings.Fields(spec)
+	if len(keys) != KeyboardSize {
+		return Keymap{}, errors.Errorf("unknown keymap %q", spec)
+	}
+	keymap := Keymap{Name: "custom"}
+	for i, key := range keys {
+		key = normalizeKey(key)
+		if !IsKeyName(key) {
+			return Keymap{}, errors.Errorf("invalid key %q", key)
+		}
+		for _, k := range keymap.Keys[:i] {
+			if k == key {
+				return Keymap{}, errors.Errorf("key %q mapped twice", key)
+			}
+		}
+		keymap.Keys[i] = key
+	}
+	return keymap, nil
+}
+
+// Spec returns the keys of the keymap as parsed by ParseKeymap.
+func (k Keymap) Spec() string {
+	return strings.Join(k.Keys[:], " ")
+}
+
+func (k Keymap) String() string {
+	return k.Name
+}
+
+// IsKeyName tells if a key of a keymap is a printable key or a key of the numeric keypad.
+func IsKeyName(name string) bool {
+	if strings.HasPrefix(name, "KP") && len(name) > 2 {
+		_, ok := keypadChars[name]
+		return ok
+	}
+	return len(name) == 1 && name[0] > ' ' && name[0] < 0x7F
+}
+
+// normalizeKey returns the name of a key written in any case.
+func normalizeKey(name string) string {
+	for kp := range keypadChars {
+		if strings.EqualFold(kp, name) {
+			return kp
+		}
+	}
+	return strings.ToUpper(name)
+}
+
+// keypadChars are the characters typed by the keys of the numeric keypad.
+var keypadChars = map[string]byte{
+	"KP0": '0', "KP1": '1', "KP2": '2', "KP3": '3', "KP4": '4',
+	"KP5": '5', "KP6": '6', "KP7": '7', "KP8": '8', "KP9": '9',
+	"KP/": '/', "KP*": '*', "KP-": '-', "KP+": '+', "KP.": '.', "KPEnter": '\r',
+}
+
+// numberRows are the characters typed by the number row, from 1 to 0, on the layouts where it doesn't type the digits
+// unless shifted, such as AZERTY. The keymaps name the keys of the number row after their digits on every layout.
+var numberRows = []string{
+	"&é\"'(-è_çà", // French AZERTY
+	"&é\"'(§è!çà", // Belgian AZERTY
+}
+
+// Chars returns the keys of the keypad typed by the characters of the printable keys, in lowercase,
+// for the frontends receiving characters rather than keys. The keys of the number row also type the characters
+// of the layouts where it doesn't type the digits, unless the keymap names these characters for other keys.
+func (k Keymap) Chars() map[rune]int {
+	chars := make(map[rune]int)
+	for i, name := range k.Keys {
+		if len(name) == 1 && name[0] >= '0' && name[0] <= '9' {
+			for _, row := range numberRows {
+				chars[[]rune(row)[(name[0]-'0'+9)%10]] = i // the row starts with 1
+			}
+		}
+	}
+	for i, name := range k.Keys {
+		if _, ok := keypadChars[name]; !ok {
+			chars[unicode.ToLower(rune(name[0]))] = i
+		}
+	}
+	return chars
+}
+
+// KeymapConfig is the configuration file of the keymaps. It is written in JSON:
+//
+//	{
+//		"default": "azerty",
+//		"keymaps": {"arrows": "X 1 2 3 Q W E A S D Z C 4 R F V"},
+//		"roms": {"brix.rom": "arrows"}
+//	}
+//
+// The keymaps are the names of builtin keymaps, of the keymaps of the file, or keymaps written as for ParseKeymap.
+type KeymapConfig struct {
+	Default string            `json:"default,omitempty"` // keymap of the ROMs without their own
+	Keymaps map[string]string `json:"keymaps,omitempty"` // custom keymaps by name
+	ROMs    map[string]string `json:"roms,omitempty"`    // keymaps of the ROMs, by name of ROM
+}
+
+// DefaultKeymapConfig returns the path of the configuration file of the keymaps in the configuration directory of the user.
+func DefaultKeymapConfig() string {
+	return filepath.Join(configDir(), "keymaps.json")
+}
+
+// configDir returns the directory of the configuration files in the configuration directory of the user.
+func configDir() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "chip8")
+}
+
+// LoadKeymapConfig reads a configuration file of the keymaps. A missing file is an empty configuration.
+func LoadKeymapConfig(filename string) (*KeymapConfig, error) {
+	config := &KeymapConfig{}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load keymaps")
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to load keymaps from %s", filename)
+	}
+	return config, nil
+}
+
+// Save writes the configuration file.
+func (c *KeymapConfig) Save(filename string) error {
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to save keymaps")
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return errors.Wrap(err, "failed to save keymaps")
+	}
+	return errors.Wrap(ioutil.WriteFile(filename, append(data, '\n'), 0644), "failed to save keymaps")
+}
+
+// Keymap returns the keymap of a ROM.
+func (c *KeymapConfig) Keymap(rom *ROM) (Keymap, error) {
+	spec := c.ROMs[rom.Name]
+	if spec == "" {
+		spec = c.Default
+	}
+	if spec == "" {
+		return Keymaps[0], nil
+	}
+	return c.parse(spec)
+}
+
+// SetKeymap sets the keymap of a ROM, by name when it is a builtin keymap or a keymap of the file.
+func (c *KeymapConfig) SetKeymap(rom *ROM, keymap Keymap) {
+	if c.ROMs == nil {
+		c.ROMs = make(map[string]string)
+	}
+	spec := keymap.Spec()
+	if named, err := c.parse(keymap.Name); err == nil && named.Keys == keymap.Keys {
+		spec = keymap.Name
+	}
+	c.ROMs[rom.Name] = spec
+}
+
+// parse returns the keymap of the file with the given name, or parses it.
+func (c *KeymapConfig) parse(spec string) (Keymap, error) {
+	if custom, ok := c.Keymaps[spec]; ok {
+		keymap, err := ParseKeymap(custom)
+		keymap.Name = spec
+		return keymap, err
+	}
+	return ParseKeymap(spec)
+}
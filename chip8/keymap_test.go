@@ -0,0 +1,81 @@
+package chip8_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeymap(t *testing.T) {
+	keymap, err := chip8.ParseKeymap("azerty")
+	require.NoError(t, err)
+	require.Equal(t, "A", keymap.Keys[4])
+
+	keymap, err = chip8.ParseKeymap("kp0 kp7 kp8 kp9 kp4 kp5 kp6 kp1 kp2 kp3 kp. kpenter kp/ kp* kp- kp+")
+	require.NoError(t, err)
+	require.Equal(t, chip8.Keymaps[3].Keys, keymap.Keys)
+
+	_, err = chip8.ParseKeymap("x 1 2 3 q w e a s d z c 4 r f")
+	require.Error(t, err)
+	_, err = chip8.ParseKeymap("x 1 2 3 q w e a s d z c 4 r f x")
+	require.Error(t, err)
+	_, err = chip8.ParseKeymap("x 1 2 3 q w e a s d z c 4 r f Tab")
+	require.Error(t, err)
+}
+
+func TestKeymapConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keymaps")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "chip8", "keymaps.json")
+
+	config, err := chip8.LoadKeymapConfig(filename)
+	require.NoError(t, err)
+	brix, pong := &chip8.ROM{Name: "brix.rom"}, &chip8.ROM{Name: "pong.rom"}
+	keymap, err := config.Keymap(brix)
+	require.NoError(t, err)
+	require.Equal(t, chip8.Keymaps[0], keymap)
+
+	config.Default = "dvorak"
+	config.Keymaps = map[string]string{"mine": "0 1 2 3 4 5 6 7 8 9 A B C D E F"}
+	config.SetKeymap(brix, chip8.Keymaps[1])
+	custom, err := chip8.ParseKeymap("0 1 2 3 4 5 6 7 8 9 A B C D E F")
+	require.NoError(t, err)
+	config.SetKeymap(pong, custom)
+	require.NoError(t, config.Save(filename))
+
+	config, err = chip8.LoadKeymapConfig(filename)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"brix.rom": "azerty", "pong.rom": "0 1 2 3 4 5 6 7 8 9 A B C D E F"}, config.ROMs)
+	keymap, err = config.Keymap(brix)
+	require.NoError(t, err)
+	require.Equal(t, "azerty", keymap.Name)
+	keymap, err = config.Keymap(&chip8.ROM{Name: "invaders.rom"})
+	require.NoError(t, err)
+	require.Equal(t, "dvorak", keymap.Name)
+}
+
+func TestKeymapChars(t *testing.T) {
+	azerty, err := chip8.ParseKeymap("azerty")
+	require.NoError(t, err)
+	chars := azerty.Chars()
+	require.Equal(t, 0x1, chars['1'])
+	require.Equal(t, 0x1, chars['&'])
+	require.Equal(t, 0x2, chars['é'])
+	require.Equal(t, 0x3, chars['"'])
+	require.Equal(t, 0xC, chars['\''])
+	require.Equal(t, 0x4, chars['a'])
+
+	// the characters named by the keymap are not those of the number row
+	dvorak, err := chip8.ParseKeymap("dvorak")
+	require.NoError(t, err)
+	require.Equal(t, 0x4, dvorak.Chars()['\''])
+
+	numpad, err := chip8.ParseKeymap("numpad")
+	require.NoError(t, err)
+	require.Empty(t, numpad.Chars())
+}
@@ -0,0 +1,125 @@
+package chip8
+
+import "sync"
+
+// Keypad is the state of the keys, fed by the frontends with the presses and releases of the keys.
+// The frontends may run in other goroutines than the emulation.
+//
+// The presses are latched so the keys tapped between two frames are seen as held during the next frame,
+// and the presses and releases are latched for Fx0A, which waits for a key to be pressed then released.
+type Keypad struct {
+	mu       sync.Mutex
+	held     [KeyboardSize]bool
+	tapped   [KeyboardSize]bool // pressed since the end of the last frame
+	pressed  [KeyboardSize]bool // pressed since the last call to takePress
+	released [KeyboardSize]bool // released since the last call to takeRelease
+}
+
+// Press presses a key, from 0 to F.
+func (k *Keypad) Press(key int) {
+	k.Set(key, true)
+}
+
+// Release releases a key, from 0 to F.
+func (k *Keypad) Release(key int) {
+	k.Set(key, false)
+}
+
+// Set presses or releases a key, from 0 to F. Setting a key in its current state does nothing.
+func (k *Keypad) Set(key int, pressed bool) {
+	if key < 0 || key >= KeyboardSize {
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.held[key] == pressed {
+		return
+	}
+	k.held[key] = pressed
+	if pressed {
+		k.tapped[key] = true
+		k.pressed[key] = true
+		k.released[key] = false // a release before the press doesn't end it
+	} else {
+		k.released[key] = true
+	}
+}
+
+// ReleaseAll releases the keys held.
+func (k *Keypad) ReleaseAll() {
+	for key := 0; key < KeyboardSize; key++ {
+		k.Release(key)
+	}
+}
+
+// Held returns the keys held.
+func (k *Keypad) Held() [KeyboardSize]bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.held
+}
+
+// Down tells if a key is held, or was tapped since the end of the last frame. The keys out of the keypad are up.
+func (k *Keypad) Down(key int) bool {
+	if key < 0 || key >= KeyboardSize {
+		return false
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.held[key] || k.tapped[key]
+}
+
+// endFrame forgets the keys tapped during the frame.
+func (k *Keypad) endFrame() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.tapped = [KeyboardSize]bool{}
+}
+
+// clearEdges forgets the presses and releases latched.
+func (k *Keypad) clearEdges() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.pressed = [KeyboardSize]bool{}
+	k.released = [KeyboardSize]bool{}
+}
+
+// takePress returns the lowest key pressed since the last call, or -1, and forgets its press.
+func (k *Keypad) takePress() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for key, pressed := range k.pressed {
+		if pressed {
+			k.pressed[key] = false
+			return key
+		}
+	}
+	return -1
+}
+
+// takeRelease tells if a key was released since the last call, and forgets its release.
+func (k *Keypad) takeRelease(key int) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	released := k.released[key]
+	k.released[key] = false
+	return released
+}
+
+// reset releases the keys and forgets what was latched.
+func (k *Keypad) reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.held = [KeyboardSize]bool{}
+	k.tapped = [KeyboardSize]bool{}
+	k.pressed = [KeyboardSize]bool{}
+	k.released = [KeyboardSize]bool{}
+}
+
+// set restores the keys held, without presses nor releases.
+func (k *Keypad) set(held [KeyboardSize]bool) {
+	k.reset()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.held = held
+}
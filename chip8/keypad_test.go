@@ -0,0 +1,94 @@
+package chip8_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeypadTap(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0x60, 0x03, // V0 = 3
+		0xE0, 0x9E, // skip if the key V0 is pressed
+		0x12, 0x02, // jump back
+		0x61, 0x01, // V1 = 1
+		0x12, 0x08, // loop forever
+	}}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	require.True(t, emulator.Frame())
+	require.Equal(t, uint8(0), emulator.State().V[1])
+
+	// a key pressed and released between two frames is seen by the next frame
+	emulator.Keypad().Press(3)
+	emulator.Keypad().Release(3)
+	require.True(t, emulator.Frame())
+	require.Equal(t, uint8(1), emulator.State().V[1])
+	require.False(t, emulator.Keypad().Down(3))
+
+	// the keys out of the keypad are ignored
+	emulator.Keypad().Press(chip8.KeyboardSize)
+	require.False(t, emulator.Keypad().Down(chip8.KeyboardSize))
+	require.False(t, emulator.Keypad().Down(-1))
+}
+
+func TestWaitKey(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0xF1, 0x0A, // V1 = key
+		0x12, 0x02, // loop forever
+	}}
+	var trace bytes.Buffer
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(&trace)
+	emulator.Reset()
+	keypad := emulator.Keypad()
+
+	// the key held before the wait is ignored
+	keypad.Press(5)
+	require.True(t, emulator.Frame())
+	keypad.Release(5)
+	require.True(t, emulator.Frame())
+	require.Equal(t, uint16(0x200), emulator.State().PC)
+
+	// the wait ends when the key pressed is released
+	keypad.Press(7)
+	require.True(t, emulator.Frame())
+	require.Equal(t, uint16(0x200), emulator.State().PC)
+	keypad.Release(7)
+	require.True(t, emulator.Frame())
+	require.Equal(t, uint8(7), emulator.State().V[1])
+
+	// the frames spent waiting execute the instruction once
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	require.Len(t, lines, 4+chip8.InstructionsPerFrame-1)
+}
+
+func TestWaitKeyPressedAgain(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0xF0, 0x0A, // V0 = key
+		0x12, 0x02, // loop forever
+	}}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	keypad := emulator.Keypad()
+
+	// the key held before the wait is released during the wait, then pressed again
+	keypad.Press(5)
+	require.True(t, emulator.Frame())
+	keypad.Release(5)
+	require.True(t, emulator.Frame())
+	keypad.Press(5)
+	require.True(t, emulator.Frame())
+	require.Equal(t, uint16(0x200), emulator.State().PC)
+	require.Equal(t, uint8(0), emulator.State().V[0])
+
+	// the wait ends when it is released
+	keypad.Release(5)
+	require.True(t, emulator.Frame())
+	require.Equal(t, uint8(5), emulator.State().V[0])
+}
@@ -0,0 +1,224 @@
+package chip8
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ThumbnailFrames = 3 * TimerFrequency // frames run to make the thumbnail of a ROM
+	MaxRecent       = 10                 // recent ROMs remembered
+)
+
+// romExtensions are the extensions of the files listed as ROMs.
+var romExtensions = []string{".rom", ".ch8", ".c8"}
+
+// RomInfo is a ROM listed by the launcher.
+type RomInfo struct {
+	Path      string
+	ROM       *ROM
+	Hash      string
+	Thumbnail Screen // screen after running the ROM for ThumbnailFrames
+	Recent    bool   // the ROM was run recently
+}
+
+// NewRomInfo loads a ROM and makes its thumbnail.
+func NewRomInfo(filename string) (RomInfo, error) {
+	rom, err := NewROM(filename)
+	if err != nil {
+		return RomInfo{}, err
+	}
+	return RomInfo{Path: filename, ROM: rom, Hash: rom.Hash(), Thumbnail: Thumbnail(rom, ThumbnailFrames)}, nil
+}
+
+// ScanROMs returns the ROMs of a directory, by name. The files which are not valid ROMs are skipped.
+func ScanROMs(dir string) ([]RomInfo, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list roms")
+	}
+	var roms []RomInfo
+	for _, file := range files {
+		if !file.Mode().IsRegular() || !isROM(file.Name()) {
+			continue
+		}
+		info, err := NewRomInfo(filepath.Join(dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		roms = append(roms, info)
+	}
+	return roms, nil
+}
+
+func isROM(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range romExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Thumbnail runs a ROM without input for a number of frames, or until it ends, and returns its screen.
+// A ROM crashing the emulator has a blank thumbnail.
+func Thumbnail(rom *ROM, frames int) (screen Screen) {
+	defer func() {
+		if recover() != nil {
+			screen = Screen{}
+		}
+	}()
+	emulator := NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	for i := 0; i < frames && emulator.Frame(); i++ {
+	}
+	return emulator.Screen()
+}
+
+// Settings are the settings of the emulation remembered for a ROM. The empty ones are left as they are.
+type Settings struct {
+	Theme  string `json:"theme,omitempty"`  // as parsed by ParseTheme
+	Pixels string `json:"pixels,omitempty"` // as parsed by ParsePixelStyle
+	Speed  int    `json:"speed,omitempty"`  // instructions per frame
+	Quirks string `json:"quirks,omitempty"` // as parsed by ParseQuirks
+}
+
+// Settings returns the current settings of the emulation.
+func (emulator *Emulator) Settings() Settings {
+	return Settings{
+		Theme:  emulator.display.Theme().Name,
+		Pixels: emulator.display.style.String(),
+		Speed:  emulator.speed,
+		Quirks: emulator.quirks.String(),
+	}
+}
+
+// Apply sets the settings of an emulator.
+func (s Settings) Apply(emulator *Emulator) error {
+	if s.Theme != "" {
+		theme, err := ParseTheme(s.Theme)
+		if err != nil {
+			return err
+		}
+		emulator.SetTheme(theme)
+	}
+	if s.Pixels != "" {
+		style, err := ParsePixelStyle(s.Pixels)
+		if err != nil {
+			return err
+		}
+		emulator.SetPixelStyle(style)
+	}
+	if s.Speed != 0 {
+		emulator.SetSpeed(s.Speed)
+	}
+	if s.Quirks != "" {
+		quirks, err := ParseQuirks(s.Quirks)
+		if err != nil {
+			return err
+		}
+		emulator.SetQuirks(quirks)
+	}
+	return nil
+}
+
+// Library is the file remembering the ROMs run recently and the settings of the ROMs, in JSON.
+// The settings are kept by hash of ROM, so they follow a ROM renamed.
+type Library struct {
+	Recent []string            `json:"recent,omitempty"` // paths of the ROMs, the last run first
+	ROMs   map[string]Settings `json:"roms,omitempty"`
+}
+
+// DefaultLibrary returns the path of the library in the configuration directory of the user.
+func DefaultLibrary() string {
+	return filepath.Join(configDir(), "library.json")
+}
+
+// LoadLibrary reads a library. A missing file is an empty library.
+func LoadLibrary(filename string) (*Library, error) {
+	library := &Library{}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return library, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load library")
+	}
+	if err := json.Unmarshal(data, library); err != nil {
+		return nil, errors.Wrapf(err, "failed to load library from %s", filename)
+	}
+	return library, nil
+}
+
+// Save writes the library.
+func (l *Library) Save(filename string) error {
+	data, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to save library")
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return errors.Wrap(err, "failed to save library")
+	}
+	return errors.Wrap(ioutil.WriteFile(filename, append(data, '\n'), 0644), "failed to save library")
+}
+
+// AddRecent puts a ROM first in the recent ROMs.
+func (l *Library) AddRecent(filename string) {
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	recent := []string{filename}
+	for _, f := range l.Recent {
+		if f != filename && len(recent) < MaxRecent {
+			recent = append(recent, f)
+		}
+	}
+	l.Recent = recent
+}
+
+// Settings returns the settings of a ROM, empty when none were remembered.
+func (l *Library) Settings(rom *ROM) Settings {
+	return l.ROMs[rom.Hash()]
+}
+
+// SetSettings remembers the settings of a ROM.
+func (l *Library) SetSettings(rom *ROM, settings Settings) {
+	if l.ROMs == nil {
+		l.ROMs = make(map[string]Settings)
+	}
+	l.ROMs[rom.Hash()] = settings
+}
+
+// List lists the recent ROMs, then the ROMs of a directory not run recently.
+// The recent ROMs that can't be loaded anymore are skipped.
+func (l *Library) List(dir string) ([]RomInfo, error) {
+	var roms []RomInfo
+	seen := make(map[string]bool)
+	for _, filename := range l.Recent {
+		info, err := NewRomInfo(filename)
+		if err != nil {
+			continue
+		}
+		info.Recent = true
+		roms = append(roms, info)
+		seen[filename] = true
+	}
+	scanned, err := ScanROMs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range scanned {
+		if abs, err := filepath.Abs(info.Path); err == nil && seen[abs] {
+			continue
+		}
+		roms = append(roms, info)
+	}
+	return roms, nil
+}
@@ -0,0 +1,58 @@
+package chip8_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibrary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "library")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// draws the sprite of 0 then loops forever
+	draw := []byte{0xA0, 0x00, 0xD0, 0x05, 0x12, 0x04}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "draw.ch8"), draw, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "loop.rom"), []byte{0x12, 0x00}, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "large.ch8"), make([]byte, 4000), 0644)) // skipped
+
+	roms, err := chip8.ScanROMs(dir)
+	require.NoError(t, err)
+	require.Len(t, roms, 2)
+	require.Equal(t, "draw.ch8", roms[0].ROM.Name)
+	require.Equal(t, "8a27044454cd6149b392022c5a10d6c93eed2622", roms[0].Hash)
+	require.NotEqual(t, chip8.Screen{}, roms[0].Thumbnail)
+	require.Equal(t, chip8.Screen{}, roms[1].Thumbnail)
+	require.Equal(t, chip8.Screen{}, chip8.Thumbnail(&chip8.ROM{Name: "large", Data: make([]byte, 4000)}, 10))
+
+	filename := filepath.Join(dir, "chip8", "library.json")
+	library, err := chip8.LoadLibrary(filename)
+	require.NoError(t, err)
+	library.AddRecent(roms[1].Path)
+	library.AddRecent(roms[0].Path)
+	library.AddRecent(roms[1].Path)
+	library.SetSettings(roms[0].ROM, chip8.Settings{Theme: "amber", Speed: 30, Quirks: "vip"})
+	require.NoError(t, library.Save(filename))
+
+	library, err = chip8.LoadLibrary(filename)
+	require.NoError(t, err)
+	listed, err := library.List(dir)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	require.Equal(t, "loop.rom", listed[0].ROM.Name)
+	require.True(t, listed[0].Recent)
+	require.True(t, listed[1].Recent)
+
+	emulator := chip8.NewEmulator(roms[0].ROM)
+	require.NoError(t, library.Settings(roms[0].ROM).Apply(emulator))
+	settings := emulator.Settings()
+	require.Equal(t, "amber", settings.Theme)
+	require.Equal(t, 30, settings.Speed)
+	require.Equal(t, "shift,loadstore,clip,vfreset", settings.Quirks)
+	require.Equal(t, chip8.Settings{}, library.Settings(roms[1].ROM))
+}
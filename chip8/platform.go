@@ -0,0 +1,26 @@
+package chip8
+
+// Platforms for which ROMs are written.
+const (
+	PlatformCHIP8     = "CHIP-8"
+	PlatformSuperCHIP = "SUPER-CHIP"
+	PlatformXOCHIP    = "XO-CHIP"
+)
+
+// DetectPlatform guesses the platform of a ROM from the instructions only the extensions of CHIP-8 have,
+// which data may look like.
+// Only CHIP-8 ROMs run on the emulator.
+func DetectPlatform(data []byte) string {
+	platform := PlatformCHIP8
+	for i := 0; i+1 < len(data); i += InstructionSize {
+		val := uint16(data[i])<<8 | uint16(data[i+1])
+		switch {
+		case val == 0xF000, val == 0xF002, val&0xF00F == 0x5002, val&0xF00F == 0x5003, val&0xF0FF == 0xF001:
+			return PlatformXOCHIP
+		case val&0xFFF0 == 0x00C0 && val != 0x00C0, val >= 0x00FB && val <= 0x00FF,
+			val&0xF0FF == 0xF030, val&0xF0FF == 0xF075, val&0xF0FF == 0xF085:
+			platform = PlatformSuperCHIP
+		}
+	}
+	return platform
+}
@@ -0,0 +1,64 @@
+package chip8
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Quirks select between the behaviors of the interpreters for the instructions they disagree on.
+// The zero value is the behavior of the emulator, the one most games expect.
+type Quirks struct {
+	ShiftVy    bool // 8xy6 and 8xyE shift Vy into Vx instead of shifting Vx, as on the COSMAC VIP
+	IncrementI bool // Fx55 and Fx65 leave I after the last register accessed, as on the COSMAC VIP
+	JumpVx     bool // Bxnn jumps to xnn + Vx instead of nnn + V0, as on the SUPER-CHIP
+	Clip       bool // sprites are clipped at the edges of the screen instead of wrapping around
+	ResetVF    bool // 8xy1, 8xy2 and 8xy3 reset VF, as on the COSMAC VIP
+}
+
+var quirkNames = []string{"shift", "loadstore", "jump", "clip", "vfreset"}
+
+// flags returns the fields of the quirks in the order of their names.
+func (q *Quirks) flags() []*bool {
+	return []*bool{&q.ShiftVy, &q.IncrementI, &q.JumpVx, &q.Clip, &q.ResetVF}
+}
+
+// ParseQuirks returns the quirks named in a comma separated list,
+// or the quirks of a platform: "vip" for the COSMAC VIP and "schip" for the SUPER-CHIP.
+func ParseQuirks(spec string) (Quirks, error) {
+	var quirks Quirks
+	switch spec {
+	case "", "none":
+		return quirks, nil
+	case "vip":
+		return Quirks{ShiftVy: true, IncrementI: true, ResetVF: true, Clip: true}, nil
+	case "schip":
+		return Quirks{JumpVx: true, Clip: true}, nil
+	}
+	flags := quirks.flags()
+next:
+	for _, name := range strings.Split(spec, ",") {
+		for i, n := range quirkNames {
+			if n == strings.TrimSpace(name) {
+				*flags[i] = true
+				continue next
+			}
+		}
+		return Quirks{}, errors.Errorf("unknown quirk %q", name)
+	}
+	return quirks, nil
+}
+
+// String returns the names of the quirks enabled, separated by commas.
+func (q Quirks) String() string {
+	var names []string
+	for i, flag := range q.flags() {
+		if *flag {
+			names = append(names, quirkNames[i])
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
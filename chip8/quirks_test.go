@@ -0,0 +1,52 @@
+package chip8_test
+
+import (
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuirks(t *testing.T) {
+	quirks, err := chip8.ParseQuirks("shift, clip")
+	require.NoError(t, err)
+	require.Equal(t, chip8.Quirks{ShiftVy: true, Clip: true}, quirks)
+	require.Equal(t, "shift,clip", quirks.String())
+
+	quirks, err = chip8.ParseQuirks("vip")
+	require.NoError(t, err)
+	require.True(t, quirks.IncrementI)
+
+	_, err = chip8.ParseQuirks("shift,foo")
+	require.Error(t, err)
+}
+
+func TestQuirks(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0x60, 0x01, // V0 = 1
+		0x61, 0x06, // V1 = 6
+		0x80, 0x16, // SHR V0, V1
+		0xA3, 0x00, // I = 0x300
+		0xF1, 0x55, // store V0, V1
+		0x12, 0x0A, // loop forever
+	}}
+	run := func(quirks chip8.Quirks) chip8.State {
+		emulator := chip8.NewEmulator(rom)
+		emulator.SetTrace(nil)
+		emulator.SetQuirks(quirks)
+		emulator.Reset()
+		require.True(t, emulator.Frame())
+		return emulator.State()
+	}
+
+	state := run(chip8.Quirks{})
+	require.Equal(t, uint8(0), state.V[0])
+	require.Equal(t, uint8(1), state.V[0xF])
+	require.Equal(t, uint16(0x300), state.I)
+
+	state = run(chip8.Quirks{ShiftVy: true, IncrementI: true})
+	require.Equal(t, uint8(3), state.V[0])
+	require.Equal(t, uint8(0), state.V[0xF])
+	require.Equal(t, uint16(0x302), state.I)
+	require.Equal(t, []byte{3, 6}, state.RAM[0x300:0x302])
+}
@@ -1,17 +1,51 @@
 package chip8
 
 type RAM struct {
-	data [RamSize]byte
+	data   [RamSize]byte
+	writes [RamSize]uint32 // number of writes of the program to every byte
 }
 
 func NewRAM() *RAM {
 	return &RAM{}
 }
 
-func (r *RAM) LoadRom(rom *ROM) {
-	for i, b := range rom.Data {
-		r.data[ProgramLocation+i] = b
+// LoadRom copies the ROM at ProgramLocation, or returns an error when it doesn't fit.
+func (r *RAM) LoadRom(rom *ROM) error {
+	if err := rom.Check(); err != nil {
+		return err
 	}
+	copy(r.data[ProgramLocation:], rom.Data)
+	return nil
+}
+
+// Read returns the byte at an address, 0 out of the memory.
+func (r *RAM) Read(addr uint16) byte {
+	if addr >= RamSize {
+		return 0
+	}
+	return r.data[addr]
+}
+
+// Peek returns the byte at an address, reading the RAM having no side effect.
+func (r *RAM) Peek(addr uint16) byte {
+	return r.Read(addr)
+}
+
+// Write stores a byte written by the program, and counts it. The writes out of the memory are ignored.
+func (r *RAM) Write(addr uint16, b byte) {
+	if addr >= RamSize {
+		return
+	}
+	r.data[addr] = b
+	r.writes[addr]++
+}
+
+// Writes returns the number of writes of the program to the byte at an address, 0 out of the memory.
+func (r *RAM) Writes(addr uint16) uint32 {
+	if addr >= RamSize {
+		return 0
+	}
+	return r.writes[addr]
 }
 
 func (r *RAM) LoadFont(font [80]byte) {
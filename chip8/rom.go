@@ -1,6 +1,8 @@
 package chip8
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"io/ioutil"
 	"path"
 
@@ -18,5 +20,23 @@ func NewROM(filename string) (*ROM, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to load rom")
 	}
-	return &ROM{Name: name, Data: data}, nil
+	rom := &ROM{Name: name, Data: data}
+	if err := rom.Check(); err != nil {
+		return nil, err
+	}
+	return rom, nil
+}
+
+// Check returns an error when the ROM doesn't fit in the memory, after ProgramLocation.
+func (rom *ROM) Check() error {
+	if len(rom.Data) > MaxRomSize {
+		return errors.Errorf("ROM %s of %d bytes too large, %d bytes at most", rom.Name, len(rom.Data), MaxRomSize)
+	}
+	return nil
+}
+
+// Hash returns the SHA-1 of the ROM in hexadecimal, which identifies it whatever its name.
+func (rom *ROM) Hash() string {
+	sum := sha1.Sum(rom.Data)
+	return hex.EncodeToString(sum[:])
 }
@@ -1,6 +1,8 @@
 package chip8_test
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/gemulation/chip8/chip8"
@@ -15,3 +17,25 @@ func TestNewRom(t *testing.T) {
 	require.Equal(t, "pong.rom", rom.Name)
 	require.True(t, len(rom.Data) > 0)
 }
+
+func TestLargeRom(t *testing.T) {
+	file, err := ioutil.TempFile("", "large*.ch8")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.Write(make([]byte, chip8.MaxRomSize+1))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, err = chip8.NewROM(file.Name())
+	require.Error(t, err)
+
+	emulator := chip8.NewEmulator(&chip8.ROM{Name: "large", Data: make([]byte, 4000)})
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	require.Error(t, emulator.Fault())
+	require.False(t, emulator.Frame())
+
+	emulator = chip8.NewEmulator(&chip8.ROM{Name: "full", Data: make([]byte, chip8.MaxRomSize)})
+	emulator.Reset()
+	require.NoError(t, emulator.Fault())
+}
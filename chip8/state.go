@@ -0,0 +1,213 @@
+package chip8
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// State is a snapshot of the machine, from which the emulation can be resumed.
+// It is saved with gob by SaveState, and can be marshalled to JSON as well.
+type State struct {
+	V      [RegSize]uint8     `json:"v"`
+	I      uint16             `json:"i"`
+	PC     uint16             `json:"pc"`
+	SP     byte               `json:"sp"`
+	Stack  [StackSize]uint16  `json:"stack"`
+	DT     uint16             `json:"dt"`
+	ST     uint16             `json:"st"`
+	RAM    [RamSize]byte      `json:"ram"`
+	Keys   [KeyboardSize]bool `json:"keys"`
+	Frame  int                `json:"frame"`
+	Screen Screen             `json:"screen"`
+}
+
+// State returns a snapshot of the machine.
+func (emulator *Emulator) State() State {
+	cpu := emulator.cpu
+	return State{
+		V:      cpu.v,
+		I:      cpu.i,
+		PC:     cpu.pc,
+		SP:     cpu.sp,
+		Stack:  cpu.stack,
+		DT:     cpu.dt,
+		ST:     cpu.st,
+		RAM:    emulator.ram.data,
+		Keys:   emulator.keypad.Held(),
+		Frame:  emulator.frame,
+		Screen: emulator.display.memory,
+	}
+}
+
+// SetState restores a snapshot of the machine.
+func (emulator *Emulator) SetState(state State) {
+	cpu := emulator.cpu
+	cpu.v, cpu.i, cpu.pc, cpu.sp, cpu.stack = state.V, state.I, state.PC, state.SP, state.Stack
+	cpu.dt, cpu.st = state.DT, state.ST
+	emulator.ram.data = state.RAM
+	emulator.keypad.set(state.Keys)
+	emulator.wait = keyWait{}
+	emulator.fault = nil
+	emulator.halt = nil
+	emulator.loops = make(map[uint16]loopState)
+	emulator.frame = state.Frame
+	emulator.display.memory = state.Screen
+	emulator.display.dirty = true
+}
+
+// SaveState writes a snapshot of the machine.
+func (emulator *Emulator) SaveState(w io.Writer) error {
+	return errors.Wrap(gob.NewEncoder(w).Encode(emulator.State()), "failed to save state")
+}
+
+// LoadState restores a snapshot written by SaveState.
+func (emulator *Emulator) LoadState(r io.Reader) error {
+	var state State
+	if err := gob.NewDecoder(r).Decode(&state); err != nil {
+		return errors.Wrap(err, "failed to load state")
+	}
+	emulator.SetState(state)
+	return nil
+}
+
+// Register designates a register of the CPU: V0 to VF are 0 to 15, followed by I, PC, SP, DT and ST.
+type Register int
+
+const (
+	RegV0 Register = 0x0
+	RegVF Register = 0xF
+	RegI  Register = 0x10
+	RegPC Register = 0x11 // address of the next instruction
+	RegSP Register = 0x12 // index of the top of the call stack
+	RegDT Register = 0x13
+	RegST Register = 0x14
+)
+
+// registerNames are the names of the registers, in order.
+var registerNames = [...]string{
+	"V0", "V1", "V2", "V3", "V4", "V5", "V6", "V7", "V8", "V9", "VA", "VB", "VC", "VD", "VE", "VF",
+	"I", "PC", "SP", "DT", "ST",
+}
+
+func (reg Register) String() string {
+	if reg >= 0 && int(reg) < len(registerNames) {
+		return registerNames[reg]
+	}
+	return fmt.Sprintf("Register(%d)", int(reg))
+}
+
+// Register returns the value of a register.
+func (emulator *Emulator) Register(reg Register) (uint16, error) {
+	cpu := emulator.cpu
+	switch {
+	case reg >= RegV0 && reg <= RegVF:
+		return uint16(cpu.v[reg]), nil
+	case reg == RegI:
+		return cpu.i, nil
+	case reg == RegPC:
+		return cpu.pc, nil
+	case reg == RegSP:
+		return uint16(cpu.sp), nil
+	case reg == RegDT:
+		return cpu.dt, nil
+	case reg == RegST:
+		return cpu.st, nil
+	}
+	return 0, errors.Errorf("unknown register %d", int(reg))
+}
+
+// SetRegister sets the value of a register. The values which don't fit the register,
+// such as a PC out of the memory, are errors.
+func (emulator *Emulator) SetRegister(reg Register, value uint16) error {
+	cpu := emulator.cpu
+	switch {
+	case reg >= RegV0 && reg <= RegVF:
+		if value > 0xFF {
+			return errors.Errorf("value %#x too large for %s", value, reg)
+		}
+		cpu.v[reg] = uint8(value)
+	case reg == RegI:
+		cpu.i = value
+	case reg == RegPC:
+		if value >= RamSize {
+			return errors.Errorf("PC %#x out of memory", value)
+		}
+		cpu.pc = value
+	case reg == RegSP:
+		if value >= StackSize {
+			return errors.Errorf("SP %d out of the stack", value)
+		}
+		cpu.sp = byte(value)
+	case reg == RegDT:
+		cpu.dt = value
+	case reg == RegST:
+		cpu.st = value
+	default:
+		return errors.Errorf("unknown register %d", int(reg))
+	}
+	return nil
+}
+
+// Peek returns the byte of the RAM at an address, without the side effects of reading it through the bus,
+// such as on the devices mapped over it.
+func (emulator *Emulator) Peek(addr uint16) (byte, error) {
+	if addr >= RamSize {
+		return 0, errors.Errorf("address %#x out of memory", addr)
+	}
+	return emulator.ram.Read(addr), nil
+}
+
+// Poke writes a byte of the RAM at an address. It is an error when a device is mapped over the address,
+// such as a read-only range, the program wouldn't see the byte.
+func (emulator *Emulator) Poke(addr uint16, b byte) error {
+	return emulator.SetMemory(addr, []byte{b})
+}
+
+// Memory returns size bytes of the RAM from an address, without the side effects of reading them through the bus.
+func (emulator *Emulator) Memory(addr uint16, size int) ([]byte, error) {
+	if size < 0 || int(addr)+size > RamSize {
+		return nil, errors.Errorf("%d bytes at %#x out of memory", size, addr)
+	}
+	data := make([]byte, size)
+	copy(data, emulator.ram.data[addr:])
+	return data, nil
+}
+
+// SetMemory writes bytes into the RAM from an address.
+// Nothing is written when they don't all fit, or when a device is mapped over one of their addresses.
+func (emulator *Emulator) SetMemory(addr uint16, data []byte) error {
+	if int(addr)+len(data) > RamSize {
+		return errors.Errorf("%d bytes at %#x out of memory", len(data), addr)
+	}
+	for i := range data {
+		if a := addr + uint16(i); emulator.memory.Mapped(a) {
+			return errors.Errorf("address %#x mapped to a device, %d bytes at %#x not written", a, len(data), addr)
+		}
+	}
+	copy(emulator.ram.data[addr:], data)
+	emulator.changes++
+	return nil
+}
+
+// Pixel tells if a pixel of the screen is on, the pixels out of the screen being off.
+func (emulator *Emulator) Pixel(x, y int) bool {
+	if x < 0 || x >= DisplayWidth || y < 0 || y >= DisplayHeight {
+		return false
+	}
+	return emulator.display.memory[y*DisplayWidth+x] != 0
+}
+
+// SetScreen replaces the pixels of the screen, row by row, those not 0 being on.
+func (emulator *Emulator) SetScreen(screen Screen) {
+	for i, pixel := range screen {
+		if pixel != 0 {
+			screen[i] = 1
+		}
+	}
+	emulator.display.memory = screen
+	emulator.display.dirty = true
+	emulator.changes++
+}
@@ -0,0 +1,144 @@
+package chip8_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveState(t *testing.T) {
+	// draws the sprite of 0, then waits for the timer in a loop
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0x60, 0x05, // V0 = 5
+		0xF0, 0x15, // DT = V0
+		0xF0, 0x29, // I = sprite of V0
+		0xD1, 0x15, // draw
+		0xF1, 0x07, // V1 = DT
+		0x31, 0x00, // skip if V1 == 0
+		0x12, 0x08, // jump back
+		0x12, 0x0E, // loop forever
+	}}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	require.True(t, emulator.Frame())
+
+	var saved bytes.Buffer
+	require.NoError(t, emulator.SaveState(&saved))
+	state := emulator.State()
+	for i := 0; i < 10; i++ {
+		require.True(t, emulator.Frame())
+	}
+	require.NotEqual(t, state, emulator.State())
+
+	require.NoError(t, emulator.LoadState(&saved))
+	require.Equal(t, state, emulator.State())
+	require.Equal(t, uint16(4), state.DT)
+	require.NotEqual(t, chip8.Screen{}, emulator.Screen())
+}
+
+func TestPause(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0x70, 0x01, // V0 += 1
+		0x12, 0x00, // jump back
+	}}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.SetSpeed(2)
+	emulator.Reset()
+	require.True(t, emulator.Frame())
+
+	emulator.SetPaused(true)
+	require.True(t, emulator.Frame())
+	require.Equal(t, 1, emulator.State().Frame)
+
+	emulator.Advance()
+	require.True(t, emulator.Frame())
+	require.True(t, emulator.Frame())
+	require.Equal(t, 2, emulator.State().Frame)
+	require.Equal(t, uint8(2), emulator.State().V[0])
+
+	emulator.SetPaused(false)
+	require.True(t, emulator.Frame())
+	require.Equal(t, 3, emulator.State().Frame)
+}
+
+func TestBreakpoint(t *testing.T) {
+	rom := &chip8.ROM{Name: "test", Data: []byte{
+		0x70, 0x01, // V0 += 1
+		0x70, 0x01, // V0 += 1
+		0x12, 0x00, // jump back
+	}}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	emulator.SetBreakpoint(0x202, true)
+	require.True(t, emulator.Frame())
+	require.True(t, emulator.Paused())
+	require.Equal(t, uint16(0x202), emulator.State().PC)
+	require.Equal(t, uint8(1), emulator.State().V[0])
+
+	// the breakpoint is passed when the emulation resumes, then stops it again
+	emulator.SetPaused(false)
+	require.True(t, emulator.Frame())
+	require.True(t, emulator.Paused())
+	require.Equal(t, uint16(0x202), emulator.State().PC)
+	require.Equal(t, uint8(3), emulator.State().V[0])
+}
+
+func TestStateAPI(t *testing.T) {
+	emulator := chip8.NewEmulator(&chip8.ROM{Name: "test", Data: []byte{0x6A, 0x42}})
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	require.True(t, emulator.Step())
+
+	value, err := emulator.Register(chip8.RegV0 + 0xA)
+	require.NoError(t, err)
+	require.Equal(t, uint16(0x42), value)
+	value, err = emulator.Register(chip8.RegPC)
+	require.NoError(t, err)
+	require.Equal(t, uint16(0x202), value)
+	require.NoError(t, emulator.SetRegister(chip8.RegI, 0x300))
+	require.NoError(t, emulator.SetRegister(chip8.RegDT, 10))
+	require.Error(t, emulator.SetRegister(chip8.RegV0, 0x100))
+	require.Error(t, emulator.SetRegister(chip8.RegPC, chip8.RamSize))
+	require.Error(t, emulator.SetRegister(chip8.RegSP, chip8.StackSize))
+	_, err = emulator.Register(chip8.RegST + 1)
+	require.Error(t, err)
+	require.Equal(t, "VA", (chip8.RegV0 + 0xA).String())
+	require.Equal(t, "PC", chip8.RegPC.String())
+
+	require.NoError(t, emulator.Poke(0x300, 0xF0))
+	require.NoError(t, emulator.SetMemory(0x301, []byte{0x90, 0xF0}))
+	b, err := emulator.Peek(0x300)
+	require.NoError(t, err)
+	require.Equal(t, byte(0xF0), b)
+	data, err := emulator.Memory(0x300, 3)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xF0, 0x90, 0xF0}, data)
+	_, err = emulator.Peek(chip8.RamSize)
+	require.Error(t, err)
+	_, err = emulator.Memory(chip8.RamSize-2, 3)
+	require.Error(t, err)
+	require.Error(t, emulator.SetMemory(chip8.RamSize-1, []byte{1, 2}))
+
+	var screen chip8.Screen
+	screen[2*chip8.DisplayWidth+3] = 0xFF
+	emulator.SetScreen(screen)
+	require.True(t, emulator.Pixel(3, 2))
+	require.False(t, emulator.Pixel(2, 3))
+	require.False(t, emulator.Pixel(-1, 0))
+	require.Equal(t, byte(1), emulator.Screen()[2*chip8.DisplayWidth+3])
+
+	state := emulator.State()
+	data, err = json.Marshal(state)
+	require.NoError(t, err)
+	var decoded chip8.State
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, state, decoded)
+	require.Equal(t, uint16(0x300), decoded.I)
+	require.Equal(t, uint16(10), decoded.DT)
+}
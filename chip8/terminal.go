@@ -0,0 +1,327 @@
+package chip8
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/color"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const DefaultKeyTimeout = 200 * time.Millisecond
+
+// TerminalMode is how the terminal frontend draws the screen.
+type TerminalMode int
+
+const (
+	HalfBlocks TerminalMode = iota // 64x16 cells, two pixels per cell
+	Braille                        // 32x8 cells, eight pixels per cell
+	Sixel                          // sixel graphics, scaled to the size of the terminal
+	terminalModes
+)
+
+var terminalModeNames = [terminalModes]string{"halfblock", "braille", "sixel"}
+
+// ParseTerminalMode returns the mode with the given name.
+func ParseTerminalMode(name string) (TerminalMode, error) {
+	for mode, n := range terminalModeNames {
+		if n == name {
+			return TerminalMode(mode), nil
+		}
+	}
+	return 0, errors.Errorf("unknown terminal mode %q", name)
+}
+
+func (m TerminalMode) String() string {
+	if m < 0 || m >= terminalModes {
+		return fmt.Sprintf("TerminalMode(%d)", int(m))
+	}
+	return terminalModeNames[m]
+}
+
+// Terminal is a frontend drawing the screen in a terminal, for instance over SSH.
+// Terminals don't report when keys are released, so a key is held until no character
+// was typed for it during KeyTimeout, which should be longer than the delay of the key repeat.
+// Ctrl-C quits.
+type Terminal struct {
+	Mode       TerminalMode
+	KeyTimeout time.Duration
+
+	emulator *Emulator
+	in       *os.File
+	out      *os.File
+	state    *termState
+	input    chan rune
+	signals  chan os.Signal
+	keymap   map[rune]int
+	held     [KeyboardSize]time.Time // when the keys are released
+	pacer    pacer
+
+	// what is drawn, to only draw again when it changes
+	screen Screen
+	theme  Theme
+	size   termSize
+	drawn  bool
+}
+
+func NewTerminal(mode TerminalMode) *Terminal {
+	return &Terminal{
+		Mode:       mode,
+		KeyTimeout: DefaultKeyTimeout,
+		in:         os.Stdin,
+		out:        os.Stdout,
+	}
+}
+
+// Open puts the terminal in raw mode and switches to the alternate screen.
+func (t *Terminal) Open(emulator *Emulator) error {
+	state, err := makeRaw(t.in)
+	if err != nil {
+		return errors.Wrap(err, "failed to open terminal")
+	}
+	t.state = state
+	t.emulator = emulator
+
+	// the keys of the numeric keypad type the same characters as the other keys
+	t.keymap = emulator.keymap.Chars()
+	for i, key := range emulator.keymap.Keys {
+		if c, ok := keypadChars[key]; ok {
+			t.keymap[rune(c)] = i
+		}
+	}
+
+	t.input = make(chan rune, 64)
+	go func() {
+		in := bufio.NewReader(t.in)
+		for {
+			c, _, err := in.ReadRune()
+			if err != nil {
+				close(t.input)
+				return
+			}
+			t.input <- c
+		}
+	}()
+	t.signals = make(chan os.Signal, 1)
+	signal.Notify(t.signals, os.Interrupt, syscall.SIGTERM)
+
+	_, err = t.out.WriteString("\x1b[?1049h\x1b[?25l\x1b[2J")
+	return err
+}
+
+// Close restores the terminal.
+func (t *Terminal) Close() error {
+	signal.Stop(t.signals)
+	t.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+	return restore(t.in, t.state)
+}
+
+// Update draws the screen when it changed, reads the keys and waits for the next frame.
+func (t *Terminal) Update() bool {
+	if !t.readKeys() {
+		return false
+	}
+	select {
+	case <-t.signals:
+		return false
+	default:
+	}
+
+	size, err := getSize(t.out)
+	if err != nil {
+		t.emulator.report(err)
+		return false
+	}
+	theme := t.emulator.display.Theme()
+	if !t.drawn || size != t.size || theme != t.theme || t.emulator.display.memory != t.screen {
+		if size != t.size {
+			t.out.WriteString("\x1b[0m\x1b[2J")
+		}
+		t.size, t.theme, t.screen, t.drawn = size, theme, t.emulator.display.memory, true
+		t.out.Write(t.draw())
+	}
+
+	t.pacer.wait()
+	return true
+}
+
+// readKeys presses the keys typed since the last frame, and releases the keys which timed out.
+// It returns false when the user quits.
+func (t *Terminal) readKeys() bool {
+	now := time.Now()
+	for {
+		select {
+		case c, ok := <-t.input:
+			if !ok || c == 0x03 || c == 0x04 { // end of input, Ctrl-C or Ctrl-D
+				return false
+			}
+			if i, ok := t.keymap[unicode.ToLower(c)]; ok {
+				t.held[i] = now.Add(t.KeyTimeout)
+			}
+			continue
+		default:
+		}
+		break
+	}
+	for i := range t.held {
+		t.emulator.keypad.Set(i, now.Before(t.held[i]))
+	}
+	return true
+}
+
+// draw returns the escape sequences drawing the screen in the middle of the terminal.
+func (t *Terminal) draw() []byte {
+	var b bytes.Buffer
+	cols, rows := DisplayWidth, DisplayHeight/2
+	switch t.Mode {
+	case Braille:
+		cols, rows = DisplayWidth/2, DisplayHeight/4
+	case Sixel:
+		cols, rows = t.sixelCells()
+	}
+	if int(t.size.cols) < cols || int(t.size.rows) < rows {
+		msg := fmt.Sprintf("terminal too small, %dx%d needed", cols, rows)
+		fmt.Fprintf(&b, "\x1b[0m\x1b[1;1H%s", msg)
+		return b.Bytes()
+	}
+	left := (int(t.size.cols)-cols)/2 + 1
+	top := (int(t.size.rows)-rows)/2 + 1
+
+	if t.Mode == Sixel {
+		fmt.Fprintf(&b, "\x1b[%d;%dH", top, left)
+		t.drawSixel(&b)
+		return b.Bytes()
+	}
+
+	fg, bg := t.theme.Foreground, t.theme.Background
+	fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%d;48;2;%d;%d;%dm", fg.R, fg.G, fg.B, bg.R, bg.G, bg.B)
+	for row := 0; row < rows; row++ {
+		fmt.Fprintf(&b, "\x1b[%d;%dH", top+row, left)
+		for col := 0; col < cols; col++ {
+			if t.Mode == Braille {
+				b.WriteRune(t.brailleCell(col, row))
+			} else {
+				b.WriteRune(t.halfBlockCell(col, row))
+			}
+		}
+	}
+	b.WriteString("\x1b[0m")
+	return b.Bytes()
+}
+
+func (t *Terminal) pixel(x, y int) bool {
+	return t.screen[y*DisplayWidth+x] == 1
+}
+
+func (t *Terminal) halfBlockCell(col, row int) rune {
+	top, bottom := t.pixel(col, row*2), t.pixel(col, row*2+1)
+	switch {
+	case top && bottom:
+		return '█'
+	case top:
+		return '▀'
+	case bottom:
+		return '▄'
+	}
+	return ' '
+}
+
+// brailleDots are the bits of the dots of a braille pattern, by row and column.
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+func (t *Terminal) brailleCell(col, row int) rune {
+	r := rune(0x2800)
+	for dy := 0; dy < 4; dy++ {
+		for dx := 0; dx < 2; dx++ {
+			if t.pixel(col*2+dx, row*4+dy) {
+				r |= brailleDots[dy][dx]
+			}
+		}
+	}
+	return r
+}
+
+// sixelScale is the size of the pixels drawn with sixels, as large as the terminal allows.
+func (t *Terminal) sixelScale() int {
+	if t.size.xpixel == 0 || t.size.ypixel == 0 {
+		return 4 // the terminal doesn't tell its size in pixels
+	}
+	scale := int(t.size.xpixel) / DisplayWidth
+	if s := int(t.size.ypixel) / DisplayHeight; s < scale {
+		scale = s
+	}
+	if scale > 1 {
+		scale-- // leave a margin
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	return scale
+}
+
+// sixelCells is the number of cells covered by the sixel image.
+func (t *Terminal) sixelCells() (cols, rows int) {
+	cellWidth, cellHeight := 8, 16
+	if t.size.xpixel != 0 && t.size.ypixel != 0 {
+		cellWidth = int(t.size.xpixel) / int(t.size.cols)
+		cellHeight = int(t.size.ypixel) / int(t.size.rows)
+	}
+	scale := t.sixelScale()
+	cols = (DisplayWidth*scale + cellWidth - 1) / cellWidth
+	rows = (DisplayHeight*scale + cellHeight - 1) / cellHeight
+	return cols, rows
+}
+
+func (t *Terminal) drawSixel(b *bytes.Buffer) {
+	scale := t.sixelScale()
+	width, height := DisplayWidth*scale, DisplayHeight*scale
+	percent := func(c color.RGBA) string {
+		return fmt.Sprintf("%d;%d;%d", int(c.R)*100/255, int(c.G)*100/255, int(c.B)*100/255)
+	}
+
+	fmt.Fprintf(b, "\x1bPq\"1;1;%d;%d", width, height)
+	fmt.Fprintf(b, "#0;2;%s#1;2;%s", percent(t.theme.Background), percent(t.theme.Foreground))
+	for band := 0; band < height; band += 6 {
+		for c := 0; c < 2; c++ {
+			fmt.Fprintf(b, "#%d", c)
+			var last byte
+			run := 0
+			for x := 0; x < width; x++ {
+				bits := byte(0)
+				for dy := 0; dy < 6 && band+dy < height; dy++ {
+					on := t.pixel(x/scale, (band+dy)/scale)
+					if on == (c == 1) {
+						bits |= 1 << uint(dy)
+					}
+				}
+				if run > 0 && bits != last {
+					writeSixels(b, last, run)
+					run = 0
+				}
+				last = bits
+				run++
+			}
+			writeSixels(b, last, run)
+			b.WriteByte('$') // back to the beginning of the band for the next color
+		}
+		b.WriteByte('-') // next band
+	}
+	b.WriteString("\x1b\\")
+}
+
+// writeSixels writes a run of identical sixels.
+func writeSixels(b *bytes.Buffer, bits byte, run int) {
+	if run > 3 {
+		fmt.Fprintf(b, "!%d%c", run, 63+bits)
+		return
+	}
+	for i := 0; i < run; i++ {
+		b.WriteByte(63 + bits)
+	}
+}
@@ -0,0 +1,52 @@
+package chip8
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+type termState struct {
+	termios syscall.Termios
+}
+
+type termSize struct {
+	rows, cols, xpixel, ypixel uint16
+}
+
+func ioctl(f *os.File, request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// makeRaw disables the echo, the line editing and the signals of the terminal, like cfmakeraw.
+func makeRaw(f *os.File) (*termState, error) {
+	var state termState
+	if err := ioctl(f, syscall.TCGETS, unsafe.Pointer(&state.termios)); err != nil {
+		return nil, err
+	}
+	raw := state.termios
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(f, syscall.TCSETS, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func restore(f *os.File, state *termState) error {
+	return ioctl(f, syscall.TCSETS, unsafe.Pointer(&state.termios))
+}
+
+func getSize(f *os.File) (termSize, error) {
+	var size termSize
+	err := ioctl(f, syscall.TIOCGWINSZ, unsafe.Pointer(&size))
+	return size, err
+}
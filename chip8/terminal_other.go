@@ -0,0 +1,30 @@
+//go:build !linux
+// +build !linux
+
+package chip8
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+type termState struct{}
+
+type termSize struct {
+	rows, cols, xpixel, ypixel uint16
+}
+
+var errNoTerminal = errors.New("the terminal frontend is only supported on linux")
+
+func makeRaw(f *os.File) (*termState, error) {
+	return nil, errNoTerminal
+}
+
+func restore(f *os.File, state *termState) error {
+	return errNoTerminal
+}
+
+func getSize(f *os.File) (termSize, error) {
+	return termSize{}, errNoTerminal
+}
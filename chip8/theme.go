@@ -0,0 +1,124 @@
+package chip8
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Theme is the palette of the display.
+type Theme struct {
+	Name       string
+	Background color.RGBA // color of the pixels that are off
+	Foreground color.RGBA // color of the pixels that are on
+}
+
+// Themes are the builtin themes, the first one being the default.
+var Themes = []Theme{
+	{"green", rgb(0xADFF2F), rgb(0x000000)},
+	{"amber", rgb(0x1A0F00), rgb(0xFFB000)},
+	{"white", rgb(0x000000), rgb(0xFFFFFF)},
+	{"octo", rgb(0x996600), rgb(0xFFCC00)},
+	{"contrast", rgb(0x000000), rgb(0xFFFF00)},
+}
+
+// ParseTheme returns the builtin theme with the given name,
+// or a custom palette written as "background,foreground" hex colors such as "#000000,#33FF66".
+func ParseTheme(spec string) (Theme, error) {
+	for _, theme := range Themes {
+		if theme.Name == spec {
+			return theme, nil
+		}
+	}
+
+	colors := strings.Split(spec, ",")
+	if len(colors) != 2 {
+		return Theme{}, errors.Errorf("unknown theme %q", spec)
+	}
+	theme := Theme{Name: spec}
+	for i, c := range []*color.RGBA{&theme.Background, &theme.Foreground} {
+		b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(colors[i]), "#"))
+		if err != nil || len(b) != 3 {
+			return Theme{}, errors.Errorf("invalid color %q", colors[i])
+		}
+		*c = color.RGBA{b[0], b[1], b[2], 0xFF}
+	}
+	return theme, nil
+}
+
+// Grid is the color of the lines of the grid pixel style, between the background and the foreground.
+func (t Theme) Grid() color.RGBA {
+	return MixRGBA(t.Background, t.Foreground, 0.25)
+}
+
+// Palette returns the colors of the theme, the background then the foreground, to draw the screen as an image.
+func (t Theme) Palette() color.Palette {
+	return color.Palette{t.Background, t.Foreground}
+}
+
+func (t Theme) String() string {
+	return t.Name
+}
+
+func rgb(c uint32) color.RGBA {
+	return color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 0xFF}
+}
+
+// PixelStyle is the shape of the pixels of the display.
+type PixelStyle int
+
+const (
+	SquarePixels PixelStyle = iota
+	RoundedPixels
+	DottedPixels
+	GridPixels
+	pixelStyles
+)
+
+var pixelStyleNames = [pixelStyles]string{"square", "rounded", "dotted", "grid"}
+
+// ParsePixelStyle returns the style with the given name.
+func ParsePixelStyle(name string) (PixelStyle, error) {
+	for style, n := range pixelStyleNames {
+		if n == name {
+			return PixelStyle(style), nil
+		}
+	}
+	return 0, errors.Errorf("unknown pixel style %q", name)
+}
+
+func (s PixelStyle) String() string {
+	if s < 0 || s >= pixelStyles {
+		return fmt.Sprintf("PixelStyle(%d)", int(s))
+	}
+	return pixelStyleNames[s]
+}
+
+// Shade tells if the point (x, y) of a pixel drawn as a square of the given size
+// is filled when the pixel is on, or is part of the grid.
+func (s PixelStyle) Shade(x, y, size int) (fill, grid bool) {
+	// distance from the center of the pixel, in pixel units
+	dx := math.Abs(float64(x)+0.5-float64(size)/2) / float64(size)
+	dy := math.Abs(float64(y)+0.5-float64(size)/2) / float64(size)
+
+	switch s {
+	case RoundedPixels:
+		const r = 0.3 // radius of the corners
+		if dx > 0.5-r && dy > 0.5-r {
+			return math.Hypot(dx-(0.5-r), dy-(0.5-r)) <= r, false
+		}
+		return true, false
+	case DottedPixels:
+		return math.Hypot(dx, dy) <= 0.35, false
+	case GridPixels:
+		if size >= 3 && (x == 0 || y == 0) {
+			return false, true
+		}
+		return true, false
+	}
+	return true, false
+}
@@ -0,0 +1,34 @@
+package chip8_test
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTheme(t *testing.T) {
+	theme, err := chip8.ParseTheme("amber")
+	require.Nil(t, err)
+	require.Equal(t, "amber", theme.Name)
+
+	theme, err = chip8.ParseTheme("#102030,40ff60")
+	require.Nil(t, err)
+	require.Equal(t, color.RGBA{0x10, 0x20, 0x30, 0xFF}, theme.Background)
+	require.Equal(t, color.RGBA{0x40, 0xFF, 0x60, 0xFF}, theme.Foreground)
+
+	_, err = chip8.ParseTheme("purple")
+	require.NotNil(t, err)
+	_, err = chip8.ParseTheme("#102030,#12345")
+	require.NotNil(t, err)
+}
+
+func TestParsePixelStyle(t *testing.T) {
+	style, err := chip8.ParsePixelStyle("dotted")
+	require.Nil(t, err)
+	require.Equal(t, chip8.DottedPixels, style)
+
+	_, err = chip8.ParsePixelStyle("hexagon")
+	require.NotNil(t, err)
+}
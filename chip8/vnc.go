@@ -0,0 +1,515 @@
+package chip8
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultVNCAddr  = "localhost:5900"
+	DefaultVNCScale = 8
+)
+
+// RFB encodings
+const (
+	rfbRaw     = 0
+	rfbRRE     = 2
+	rfbHextile = 5
+)
+
+// hextile subencodings
+const (
+	hextileBackground = 2
+	hextileForeground = 4
+	hextileSubrects   = 8
+)
+
+// pixelFormat is how a VNC client wants the colors of the pixels.
+type pixelFormat struct {
+	BitsPerPixel, Depth, BigEndian, TrueColor uint8
+	RedMax, GreenMax, BlueMax                 uint16
+	RedShift, GreenShift, BlueShift           uint8
+	_                                         [3]byte
+}
+
+var defaultPixelFormat = pixelFormat{
+	BitsPerPixel: 32, Depth: 24, TrueColor: 1,
+	RedMax: 255, GreenMax: 255, BlueMax: 255,
+	RedShift: 16, GreenShift: 8, BlueShift: 0,
+}
+
+// VNC is a frontend serving the screen to VNC viewers with the RFB 3.8 protocol, without authentication.
+// The screen is scaled up by Scale. Every viewer can press keys, typed with the same layout as in the window.
+type VNC struct {
+	Addr  string
+	Scale int
+
+	emulator *Emulator
+	listener net.Listener
+	signals  chan os.Signal
+	pacer    pacer
+	keymap   map[uint32]int // keysym to key
+
+	mu      sync.Mutex
+	clients map[*vncClient]bool
+	screen  Screen
+	theme   Theme
+}
+
+// vncClient is a connected viewer.
+type vncClient struct {
+	conn     net.Conn
+	format   pixelFormat
+	encoding int32
+	keys     [KeyboardSize]bool
+
+	requested bool // the viewer waits for an update
+	full      bool // the update must cover the whole screen
+	sent      Screen
+	sentTheme Theme
+	updates   chan []byte
+}
+
+func NewVNC(addr string) *VNC {
+	return &VNC{Addr: addr, Scale: DefaultVNCScale}
+}
+
+// Open starts accepting viewers.
+func (v *VNC) Open(emulator *Emulator) error {
+	listener, err := net.Listen("tcp", v.Addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to open vnc frontend")
+	}
+	if v.Scale < 1 {
+		v.Scale = 1
+	}
+	v.listener = listener
+	v.emulator = emulator
+	v.clients = make(map[*vncClient]bool)
+	v.screen = emulator.display.memory
+	v.theme = emulator.display.Theme()
+
+	v.keymap = keysyms(emulator.keymap)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go v.serve(conn)
+		}
+	}()
+
+	v.signals = make(chan os.Signal, 1)
+	signal.Notify(v.signals, os.Interrupt, syscall.SIGTERM)
+	return nil
+}
+
+// Close stops accepting viewers and disconnects them.
+func (v *VNC) Close() error {
+	signal.Stop(v.signals)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for client := range v.clients {
+		client.conn.Close()
+	}
+	return v.listener.Close()
+}
+
+// Update sends the screen to the viewers waiting for it and feeds their keys to the emulator.
+func (v *VNC) Update() bool {
+	select {
+	case <-v.signals:
+		return false
+	default:
+	}
+
+	v.mu.Lock()
+	v.screen = v.emulator.display.memory
+	v.theme = v.emulator.display.Theme()
+	for client := range v.clients {
+		v.update(client)
+	}
+	v.mu.Unlock()
+
+	v.pacer.wait()
+	return true
+}
+
+// update sends a framebuffer update to a viewer if it requested one and the screen changed.
+// It must be called with the lock held.
+func (v *VNC) update(client *vncClient) {
+	if !client.requested {
+		return
+	}
+	x0, y0, x1, y1 := 0, 0, DisplayWidth, DisplayHeight
+	if !client.full && client.sentTheme == v.theme {
+		// only send the rectangle containing the pixels that changed
+		x0, y0, x1, y1 = DisplayWidth, DisplayHeight, 0, 0
+		for y := 0; y < DisplayHeight; y++ {
+			for x := 0; x < DisplayWidth; x++ {
+				if i := y*DisplayWidth + x; v.screen[i] != client.sent[i] {
+					x0, y0 = minInt(x0, x), minInt(y0, y)
+					x1, y1 = maxInt(x1, x+1), maxInt(y1, y+1)
+				}
+			}
+		}
+		if x0 >= x1 {
+			return
+		}
+	}
+
+	var b bytes.Buffer
+	b.Write([]byte{0, 0}) // FramebufferUpdate
+	binary.Write(&b, binary.BigEndian, uint16(1))
+	v.encodeRect(&b, client, x0, y0, x1, y1)
+	select {
+	case client.updates <- b.Bytes():
+		client.requested, client.full = false, false
+		client.sent, client.sentTheme = v.screen, v.theme
+	default:
+	}
+}
+
+// encodeRect writes a rectangle of the screen, given in pixels of the screen, scaled up.
+func (v *VNC) encodeRect(b *bytes.Buffer, client *vncClient, x0, y0, x1, y1 int) {
+	s := v.Scale
+	binary.Write(b, binary.BigEndian, []uint16{uint16(x0 * s), uint16(y0 * s), uint16((x1 - x0) * s), uint16((y1 - y0) * s)})
+	binary.Write(b, binary.BigEndian, client.encoding)
+
+	bg := client.format.pixel(v.theme.Background)
+	fg := client.format.pixel(v.theme.Foreground)
+	lit := func(x, y int) bool { // in scaled pixels
+		return v.screen[(y/s)*DisplayWidth+x/s] == 1
+	}
+	// runs returns the lit runs of the row of the screen containing the scaled pixel row y, clipped to [from, to)
+	runs := func(y, from, to int, each func(x, w int)) {
+		for x := from; x < to; {
+			if !lit(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < to && lit(x, y) {
+				x++
+			}
+			each(start, x-start)
+		}
+	}
+
+	switch client.encoding {
+	case rfbRRE:
+		var rects bytes.Buffer
+		count := 0
+		for y := y0; y < y1; y++ {
+			runs(y*s, x0*s, x1*s, func(x, w int) {
+				rects.Write(fg)
+				binary.Write(&rects, binary.BigEndian, []uint16{uint16(x - x0*s), uint16((y - y0) * s), uint16(w), uint16(s)})
+				count++
+			})
+		}
+		binary.Write(b, binary.BigEndian, uint32(count))
+		b.Write(bg)
+		b.Write(rects.Bytes())
+	case rfbHextile:
+		for ty := y0 * s; ty < y1*s; ty += 16 {
+			th := minInt(16, y1*s-ty)
+			for tx := x0 * s; tx < x1*s; tx += 16 {
+				tw := minInt(16, x1*s-tx)
+				var rects bytes.Buffer
+				count := 0
+				for y := ty; y < ty+th; {
+					// the rows of the tile in the same row of the screen are the same
+					h := minInt((y/s+1)*s, ty+th) - y
+					runs(y, tx, tx+tw, func(x, w int) {
+						rects.Write([]byte{byte((x-tx)<<4 | (y - ty)), byte((w-1)<<4 | (h - 1))})
+						count++
+					})
+					y += h
+				}
+				if count == 0 {
+					b.WriteByte(hextileBackground)
+					b.Write(bg)
+					continue
+				}
+				b.WriteByte(hextileBackground | hextileForeground | hextileSubrects)
+				b.Write(bg)
+				b.Write(fg)
+				b.WriteByte(byte(count))
+				b.Write(rects.Bytes())
+			}
+		}
+	default:
+		for y := y0 * s; y < y1*s; y++ {
+			for x := x0 * s; x < x1*s; x++ {
+				if lit(x, y) {
+					b.Write(fg)
+				} else {
+					b.Write(bg)
+				}
+			}
+		}
+	}
+}
+
+// serve talks to a viewer.
+func (v *VNC) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	client := &vncClient{conn: conn, format: defaultPixelFormat, encoding: rfbRaw, updates: make(chan []byte, 1)}
+	if err := v.handshake(r, conn); err != nil {
+		return
+	}
+
+	v.mu.Lock()
+	v.clients[client] = true
+	v.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for update := range client.updates {
+			if _, err := conn.Write(update); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for v.receive(r, client) == nil {
+	}
+
+	v.mu.Lock()
+	delete(v.clients, client)
+	for i := range client.keys {
+		v.setKey(i)
+	}
+	close(client.updates)
+	v.mu.Unlock()
+	<-done
+}
+
+// handshake negociates the version and the security, then sends the size and the format of the framebuffer.
+func (v *VNC) handshake(r io.Reader, w io.Writer) error {
+	if _, err := io.WriteString(w, "RFB 003.008\n"); err != nil {
+		return err
+	}
+	version := make([]byte, 12)
+	if _, err := io.ReadFull(r, version); err != nil {
+		return err
+	}
+
+	switch string(version) {
+	case "RFB 003.003\n":
+		// the server decides of the security
+		if err := binary.Write(w, binary.BigEndian, uint32(1)); err != nil {
+			return err
+		}
+	case "RFB 003.007\n", "RFB 003.008\n":
+		if _, err := w.Write([]byte{1, 1}); err != nil { // one security type: None
+			return err
+		}
+		security := make([]byte, 1)
+		if _, err := io.ReadFull(r, security); err != nil {
+			return err
+		}
+		if security[0] != 1 {
+			return errors.New("unsupported security type")
+		}
+		if string(version) == "RFB 003.008\n" {
+			if err := binary.Write(w, binary.BigEndian, uint32(0)); err != nil { // SecurityResult OK
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("unsupported version %q", version)
+	}
+
+	shared := make([]byte, 1)
+	if _, err := io.ReadFull(r, shared); err != nil {
+		return err
+	}
+	name := v.emulator.rom.Name
+	init := []interface{}{
+		uint16(DisplayWidth * v.Scale), uint16(DisplayHeight * v.Scale),
+		defaultPixelFormat,
+		uint32(len(name)), []byte(name),
+	}
+	for _, field := range init {
+		if err := binary.Write(w, binary.BigEndian, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receive handles a message of a viewer.
+func (v *VNC) receive(r io.Reader, client *vncClient) error {
+	msg := make([]byte, 1)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return err
+	}
+
+	switch msg[0] {
+	case 0: // SetPixelFormat
+		var m struct {
+			_      [3]byte
+			Format pixelFormat
+		}
+		if err := binary.Read(r, binary.BigEndian, &m); err != nil {
+			return err
+		}
+		if m.Format.TrueColor == 0 {
+			return errors.New("color maps are not supported")
+		}
+		v.mu.Lock()
+		client.format = m.Format
+		client.full = true
+		v.mu.Unlock()
+	case 2: // SetEncodings
+		var m struct {
+			_     byte
+			Count uint16
+		}
+		if err := binary.Read(r, binary.BigEndian, &m); err != nil {
+			return err
+		}
+		encodings := make([]int32, m.Count)
+		if err := binary.Read(r, binary.BigEndian, encodings); err != nil {
+			return err
+		}
+		v.mu.Lock()
+		client.encoding = rfbRaw
+		for _, encoding := range encodings {
+			if encoding == rfbHextile || encoding == rfbRRE {
+				client.encoding = encoding
+				break
+			}
+		}
+		v.mu.Unlock()
+	case 3: // FramebufferUpdateRequest
+		var m struct {
+			Incremental uint8
+			X, Y, W, H  uint16
+		}
+		if err := binary.Read(r, binary.BigEndian, &m); err != nil {
+			return err
+		}
+		v.mu.Lock()
+		client.requested = true
+		client.full = client.full || m.Incremental == 0
+		if client.full {
+			v.update(client) // don't keep the viewer waiting for the next frame
+		}
+		v.mu.Unlock()
+	case 4: // KeyEvent
+		var m struct {
+			Down uint8
+			_    [2]byte
+			Key  uint32
+		}
+		if err := binary.Read(r, binary.BigEndian, &m); err != nil {
+			return err
+		}
+		if i, ok := v.keymap[m.Key]; ok {
+			v.mu.Lock()
+			client.keys[i] = m.Down != 0
+			v.setKey(i)
+			v.mu.Unlock()
+		}
+	case 5: // PointerEvent
+		_, err := io.ReadFull(r, make([]byte, 5))
+		return err
+	case 6: // ClientCutText
+		var m struct {
+			_      [3]byte
+			Length uint32
+		}
+		if err := binary.Read(r, binary.BigEndian, &m); err != nil {
+			return err
+		}
+		_, err := io.CopyN(ioutil.Discard, r, int64(m.Length))
+		return err
+	default:
+		return errors.Errorf("unsupported message %d", msg[0])
+	}
+	return nil
+}
+
+// pixel returns a color in the format of the viewer.
+func (f pixelFormat) pixel(c interface{ RGBA() (r, g, b, a uint32) }) []byte {
+	r, g, b, _ := c.RGBA()
+	value := (r*uint32(f.RedMax)/0xFFFF)<<f.RedShift |
+		(g*uint32(f.GreenMax)/0xFFFF)<<f.GreenShift |
+		(b*uint32(f.BlueMax)/0xFFFF)<<f.BlueShift
+
+	size := int(f.BitsPerPixel) / 8
+	pixel := make([]byte, size)
+	for i := 0; i < size; i++ {
+		shift := uint(8 * i)
+		if f.BigEndian != 0 {
+			shift = uint(8 * (size - 1 - i))
+		}
+		pixel[i] = byte(value >> shift)
+	}
+	return pixel
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// setKey presses a key when any viewer holds it. It must be called with the lock held.
+func (v *VNC) setKey(key int) {
+	pressed := false
+	for client := range v.clients {
+		pressed = pressed || client.keys[key]
+	}
+	v.emulator.keypad.Set(key, pressed)
+}
+
+// keypadKeysyms are the keysyms of the keys of the numeric keypad.
+var keypadKeysyms = map[string]uint32{
+	"KP0": 0xFFB0, "KP1": 0xFFB1, "KP2": 0xFFB2, "KP3": 0xFFB3, "KP4": 0xFFB4,
+	"KP5": 0xFFB5, "KP6": 0xFFB6, "KP7": 0xFFB7, "KP8": 0xFFB8, "KP9": 0xFFB9,
+	"KP/": 0xFFAF, "KP*": 0xFFAA, "KP-": 0xFFAD, "KP+": 0xFFAB, "KP.": 0xFFAE, "KPEnter": 0xFF8D,
+}
+
+// keysyms returns the keys of the keypad pressed by the keysyms. The keysyms of the printable characters
+// are their latin-1 codes, in lowercase and in uppercase.
+func keysyms(keymap Keymap) map[uint32]int {
+	keys := make(map[uint32]int)
+	for c, i := range keymap.Chars() {
+		if c < 0x100 {
+			keys[uint32(c)] = i
+			keys[uint32(unicode.ToUpper(c))] = i
+		}
+	}
+	for i, name := range keymap.Keys {
+		if keysym, ok := keypadKeysyms[name]; ok {
+			keys[keysym] = i
+		}
+	}
+	return keys
+}
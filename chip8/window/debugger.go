@@ -0,0 +1,305 @@
+package window
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/gemulation/chip8/chip8"
+	"github.com/pkg/errors"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font/basicfont"
+)
+
+const (
+	debuggerWidth  = 1000
+	debuggerHeight = 640
+	writeFade      = 60 // frames a byte written stays highlighted
+
+	// left of the panes of the debugger
+	memoryX    = 10
+	disasmX    = 400
+	registersX = 680
+	stackX     = 820
+)
+
+// debugger is a window showing the memory, the disassembly, the call stack, the sprite at I and the registers
+// of the emulator. Clicking an instruction toggles a breakpoint on it, clicking a register edits it.
+type debugger struct {
+	window   *pixelgl.Window
+	emulator *chip8.Emulator
+	atlas    *text.Atlas
+
+	ram     *chip8.RAM
+	state   chip8.State           // the emulator when last drawn
+	writes  [chip8.RamSize]uint32 // writes of the program seen, to find the bytes written since
+	written [chip8.RamSize]int    // frames left highlighting the bytes written
+	row     int                   // first row of 16 bytes of the memory shown
+	lines   []uint16              // addresses of the lines of the disassembly
+	editing int                   // register edited, or -1
+	input   string                // value typed for the register edited
+}
+
+func newDebugger(emulator *chip8.Emulator) (*debugger, error) {
+	window, err := pixelgl.NewWindow(pixelgl.WindowConfig{
+		Title:  "Debugger - " + emulator.ROM().Name,
+		Bounds: pixel.R(0, 0, debuggerWidth, debuggerHeight),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open debugger")
+	}
+	return &debugger{
+		window:   window,
+		emulator: emulator,
+		atlas:    text.NewAtlas(basicfont.Face7x13, text.ASCII),
+		row:      chip8.ProgramLocation / 16,
+		editing:  -1,
+	}, nil
+}
+
+func (d *debugger) close() {
+	d.window.Destroy()
+}
+
+// update draws the debugger and handles its input. It returns false when the window is closed.
+func (d *debugger) update() bool {
+	d.track()
+	d.draw()
+	d.window.Update()
+	if d.window.Closed() {
+		return false
+	}
+
+	if d.editing >= 0 {
+		d.edit()
+		return true
+	}
+	if d.window.JustPressed(pixelgl.KeySpace) {
+		d.emulator.SetPaused(!d.emulator.Paused())
+		d.emulator.Display().SetDirty(true)
+	}
+	if d.window.JustPressed(pixelgl.KeyN) && d.emulator.Paused() {
+		d.emulator.Step()
+	}
+	if d.window.JustPressed(pixelgl.KeyPageUp) {
+		d.scroll(-d.rows())
+	}
+	if d.window.JustPressed(pixelgl.KeyPageDown) {
+		d.scroll(d.rows())
+	}
+	mouse := d.window.MousePosition()
+	if mouse.X < disasmX {
+		d.scroll(-int(d.window.MouseScroll().Y) * 4)
+	}
+	if d.window.JustPressed(pixelgl.MouseButtonLeft) {
+		d.click(mouse)
+	}
+	return true
+}
+
+// track highlights the bytes written by the program since the last update.
+func (d *debugger) track() {
+	ram := d.emulator.RAM()
+	if ram != d.ram { // reset
+		d.ram, d.written = ram, [chip8.RamSize]int{}
+		for addr := range d.writes {
+			d.writes[addr] = ram.Writes(uint16(addr))
+		}
+	}
+	for addr := range d.writes {
+		if writes := ram.Writes(uint16(addr)); writes != d.writes[addr] {
+			d.writes[addr] = writes
+			d.written[addr] = writeFade
+		} else if d.written[addr] > 0 {
+			d.written[addr]--
+		}
+	}
+}
+
+// rows returns the number of rows of the panes.
+func (d *debugger) rows() int {
+	return int((d.window.Bounds().H()-60)/d.atlas.LineHeight()) - 1
+}
+
+func (d *debugger) scroll(rows int) {
+	d.row += rows
+	if max := chip8.RamSize/16 - d.rows(); d.row > max {
+		d.row = max
+	}
+	if d.row < 0 {
+		d.row = 0
+	}
+}
+
+// top returns the baseline of the first line of the panes.
+func (d *debugger) top() float64 {
+	return d.window.Bounds().H() - 20
+}
+
+// lineAt returns the line of the panes under a point.
+func (d *debugger) lineAt(p pixel.Vec) int {
+	return int((d.top() + d.atlas.Ascent() - p.Y) / d.atlas.LineHeight())
+}
+
+// click toggles the breakpoint of the instruction clicked, or edits the register clicked.
+func (d *debugger) click(p pixel.Vec) {
+	line := d.lineAt(p) - 1 // below the title
+	switch {
+	case p.X >= disasmX && p.X < registersX && line >= 0 && line < len(d.lines):
+		addr := d.lines[line]
+		d.emulator.SetBreakpoint(addr, !d.emulator.Breakpoint(addr))
+	case p.X >= registersX && p.X < stackX && line >= 0 && line <= int(chip8.RegST):
+		d.editing, d.input = line, ""
+	}
+}
+
+// edit types the new value of the register edited, in hexadecimal. Enter sets it, Escape cancels.
+func (d *debugger) edit() {
+	for _, c := range strings.ToUpper(d.window.Typed()) {
+		if strings.ContainsRune("0123456789ABCDEF", c) && len(d.input) < 4 {
+			d.input += string(c)
+		}
+	}
+	switch {
+	case d.window.JustPressed(pixelgl.KeyBackspace) && d.input != "":
+		d.input = d.input[:len(d.input)-1]
+	case d.window.JustPressed(pixelgl.KeyEnter):
+		if value, err := strconv.ParseUint(d.input, 16, 16); err == nil {
+			report(d.emulator.SetRegister(chip8.Register(d.editing), uint16(value)))
+		}
+		d.editing = -1
+	case d.window.JustPressed(pixelgl.KeyEscape):
+		d.editing = -1
+	}
+}
+
+// pane returns the text of a pane starting on a line.
+func (d *debugger) pane(x float64, line int, title string) *text.Text {
+	txt := text.New(pixel.V(x, d.top()-float64(line)*d.atlas.LineHeight()), d.atlas)
+	txt.Color = colornames.Lightskyblue
+	fmt.Fprintln(txt, title)
+	txt.Color = colornames.White
+	return txt
+}
+
+func (d *debugger) draw() {
+	d.state = d.emulator.State()
+	d.window.Clear(colornames.Black)
+	d.drawMemory()
+	d.drawDisassembly()
+	d.drawRegisters()
+	d.drawStack()
+	d.drawSprite()
+
+	txt := text.New(pixel.V(memoryX, 15), d.atlas)
+	txt.Color = colornames.Lightskyblue
+	state := "running"
+	if d.emulator.Paused() {
+		state = "paused"
+	}
+	fmt.Fprintf(txt, "%s - Space: pause/resume  N: step an instruction while paused  wheel, PageUp, PageDown: scroll the memory", state)
+	txt.Draw(d.window, pixel.IM)
+}
+
+// drawMemory draws the memory in hexadecimal, the bytes written lately in red.
+func (d *debugger) drawMemory() {
+	txt := d.pane(memoryX, 0, "Memory")
+	for row := d.row; row < d.row+d.rows() && row < chip8.RamSize/16; row++ {
+		txt.Color = colornames.White
+		fmt.Fprintf(txt, "%04X ", row*16)
+		for addr := row * 16; addr < row*16+16; addr++ {
+			txt.Color = chip8.MixRGBA(colornames.White, colornames.Red, float64(d.written[addr])/writeFade)
+			fmt.Fprintf(txt, " %02X", d.state.RAM[addr])
+		}
+		fmt.Fprintln(txt)
+	}
+	txt.Draw(d.window, pixel.IM)
+}
+
+// drawDisassembly draws the instructions around PC, the breakpoints marked with a star.
+func (d *debugger) drawDisassembly() {
+	txt := d.pane(disasmX, 0, "Disassembly (click: breakpoint)")
+	pc := int(d.state.PC)
+	before := d.rows() / 3
+	if before > pc/chip8.InstructionSize {
+		before = pc / chip8.InstructionSize
+	}
+	d.lines = d.lines[:0]
+	for addr := pc - before*chip8.InstructionSize; addr+1 < chip8.RamSize && len(d.lines) < d.rows(); addr += chip8.InstructionSize {
+		d.lines = append(d.lines, uint16(addr))
+		marker := " "
+		if d.emulator.Breakpoint(uint16(addr)) {
+			marker = "*"
+		}
+		txt.Color = colornames.White
+		switch {
+		case addr == pc:
+			txt.Color = colornames.Yellow
+			marker += ">"
+		case marker == "*":
+			txt.Color = colornames.Red
+			marker += " "
+		default:
+			marker += " "
+		}
+		fmt.Fprintf(txt, "%s %s\n", marker, d.emulator.Instruction(uint16(addr)))
+	}
+	txt.Draw(d.window, pixel.IM)
+}
+
+func (d *debugger) drawRegisters() {
+	txt := d.pane(registersX, 0, "Registers")
+	for reg := chip8.RegV0; reg <= chip8.RegST; reg++ {
+		if int(reg) == d.editing {
+			txt.Color = colornames.Cyan
+			fmt.Fprintf(txt, "%-2s %s_\n", reg, d.input)
+			continue
+		}
+		txt.Color = colornames.White
+		value, _ := d.emulator.Register(reg)
+		fmt.Fprintf(txt, "%-2s %04X\n", reg, value)
+	}
+	txt.Color = colornames.Lightskyblue
+	fmt.Fprint(txt, "\nclick to edit,\nEnter to set")
+	txt.Draw(d.window, pixel.IM)
+}
+
+// drawStack draws the return addresses of the call stack, the top first.
+func (d *debugger) drawStack() {
+	txt := d.pane(stackX, 0, "Call stack")
+	state := d.state
+	if state.SP == 0 {
+		fmt.Fprintln(txt, "empty")
+	}
+	for sp := int(state.SP); sp > 0 && sp < chip8.StackSize; sp-- {
+		fmt.Fprintf(txt, "%X: %04X\n", sp, state.Stack[sp])
+	}
+	txt.Draw(d.window, pixel.IM)
+}
+
+// drawSprite draws the 15 bytes at I as Draw would, with their values.
+func (d *debugger) drawSprite() {
+	const line, height, size = chip8.StackSize + 2, 15, 8
+	txt := d.pane(stackX, line, "Sprite at I")
+	pixels := imdraw.New(nil)
+	pixels.Color = colornames.White
+	origin := pixel.V(stackX+80, d.top()-float64(line)*d.atlas.LineHeight()-d.atlas.LineHeight())
+	for row := 0; row < height; row++ {
+		addr := (int(d.state.I) + row) % chip8.RamSize
+		b := d.state.RAM[addr]
+		fmt.Fprintf(txt, "%04X %02X\n", addr, b)
+		for bit := 0; bit < 8; bit++ {
+			if b&(0x80>>uint(bit)) != 0 {
+				min := origin.Add(pixel.V(float64(bit*size), -float64((row+1)*size)))
+				pixels.Push(min, min.Add(pixel.V(size-1, size-1)))
+				pixels.Rectangle(0)
+			}
+		}
+	}
+	txt.Draw(d.window, pixel.IM)
+	pixels.Draw(d.window)
+}
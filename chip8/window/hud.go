@@ -0,0 +1,89 @@
+package window
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/text"
+	"github.com/gemulation/chip8/chip8"
+)
+
+// hudStats measures the rates shown by the debug HUD, once per second.
+type hudStats struct {
+	since    time.Time
+	updates  int   // frames shown by the window
+	frame    int   // frame emulated at the start of the second
+	executed int64 // instructions executed at the start of the second
+
+	fps, frames, ips float64
+}
+
+// measure counts a frame shown and updates the rates every second.
+func (s *hudStats) measure(emulator *chip8.Emulator) {
+	now := time.Now()
+	s.updates++
+	elapsed := now.Sub(s.since).Seconds()
+	if elapsed < 1 {
+		return
+	}
+	frame, executed := emulator.Frames(), emulator.Instructions()
+	if !s.since.IsZero() && frame >= s.frame { // not across a reset
+		s.fps = float64(s.updates) / elapsed
+		s.frames = float64(frame-s.frame) / elapsed
+		s.ips = float64(executed-s.executed) / elapsed
+	}
+	s.since, s.updates = now, 0
+	s.frame, s.executed = frame, executed
+}
+
+// hud returns the text of the debug HUD: the rates of the emulation, the registers and the last instruction executed.
+func (w *Window) hud() string {
+	state := w.emulator.State()
+	s := fmt.Sprintf("%.0f fps  %.0f frames/s  %.0f instructions/s\n", w.stats.fps, w.stats.frames, w.stats.ips)
+	for i, v := range state.V {
+		s += fmt.Sprintf("V%X %02X", i, v)
+		if i%8 == 7 {
+			s += "\n"
+		} else {
+			s += "  "
+		}
+	}
+	s += fmt.Sprintf("I %04X  PC %04X  SP %X  DT %02X  ST %02X", state.I, state.PC, state.SP, state.DT, state.ST)
+	if state.ST > 0 {
+		s += "  buzzer on\n"
+	} else {
+		s += "  buzzer off\n"
+	}
+	if current := w.emulator.Current(); current != nil {
+		s += current.String()
+	}
+	return s
+}
+
+// drawHUD draws the debug HUD in the top left corner of the screen, on a dark background.
+func (w *Window) drawHUD() {
+	txt := text.New(pixel.ZV, w.atlas)
+	txt.Color = w.display.Theme().Foreground
+	fmt.Fprint(txt, w.hud())
+
+	// as large as possible while leaving most of the screen visible
+	scale := w.screen.W() * 0.6 / txt.Bounds().W()
+	if scale > 2 {
+		scale = 2
+	}
+	margin := 4.0
+	size := txt.Bounds().Size().Scaled(scale)
+	corner := pixel.V(w.screen.Min.X, w.screen.Max.Y)
+	box := pixel.R(corner.X, corner.Y-size.Y-2*margin, corner.X+size.X+2*margin, corner.Y)
+
+	background := imdraw.New(nil)
+	background.Color = color.RGBA{A: 0xC0}
+	background.Push(box.Min, box.Max)
+	background.Rectangle(0)
+	background.Draw(w.window)
+	at := pixel.V(box.Min.X+margin, box.Min.Y+margin).Sub(txt.Bounds().Min.Scaled(scale))
+	txt.Draw(w.window, pixel.IM.Scaled(pixel.ZV, scale).Moved(at))
+}
@@ -0,0 +1,188 @@
+package window
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/gemulation/chip8/chip8"
+	"github.com/pkg/errors"
+	"golang.org/x/image/font/basicfont"
+)
+
+const (
+	thumbnailScale = 3
+	cardWidth      = chip8.DisplayWidth*thumbnailScale + 20
+	cardHeight     = chip8.DisplayHeight*thumbnailScale + 60
+)
+
+// launcher is the window listing the ROMs to choose the one to run.
+type launcher struct {
+	window   *pixelgl.Window
+	atlas    *text.Atlas
+	theme    chip8.Theme
+	roms     []chip8.RomInfo
+	pictures []*pixel.Sprite
+	selected int
+	scroll   float64 // distance scrolled down
+}
+
+// Launch opens a window listing the recent ROMs of the library then the ROMs of a directory,
+// with their thumbnails, sizes and hashes. It returns the path of the ROM chosen,
+// or "" when the window is closed.
+func Launch(dir string, library *chip8.Library, theme chip8.Theme) (string, error) {
+	roms, err := library.List(dir)
+	if err != nil {
+		return "", err
+	}
+	l := &launcher{theme: theme, roms: roms}
+	for _, info := range roms {
+		l.pictures = append(l.pictures, thumbnail(info.Thumbnail, theme))
+	}
+
+	var chosen string
+	pixelgl.Run(func() {
+		l.window, err = pixelgl.NewWindow(pixelgl.WindowConfig{
+			Title:     "chip8 - " + dir,
+			Bounds:    pixel.R(0, 0, 4*cardWidth+50, 3*cardHeight+60),
+			Resizable: true,
+			VSync:     true,
+		})
+		if err != nil {
+			err = errors.Wrap(err, "failed to open launcher")
+			return
+		}
+		defer l.window.Destroy()
+		l.atlas = text.NewAtlas(basicfont.Face7x13, text.ASCII)
+		chosen = l.run()
+	})
+	return chosen, err
+}
+
+// thumbnail returns the sprite of a screen in the colors of a theme.
+func thumbnail(screen chip8.Screen, theme chip8.Theme) *pixel.Sprite {
+	picture := pixel.MakePictureData(pixel.R(0, 0, chip8.DisplayWidth, chip8.DisplayHeight))
+	for y := 0; y < chip8.DisplayHeight; y++ {
+		for x := 0; x < chip8.DisplayWidth; x++ {
+			c := theme.Background
+			if screen[y*chip8.DisplayWidth+x] != 0 {
+				c = theme.Foreground
+			}
+			// the rows of the picture go from the bottom to the top
+			picture.Pix[(chip8.DisplayHeight-1-y)*chip8.DisplayWidth+x] = c
+		}
+	}
+	return pixel.NewSprite(picture, picture.Bounds())
+}
+
+// run shows the ROMs until one is chosen with Enter or a click, or the window is closed.
+func (l *launcher) run() string {
+	for !l.window.Closed() {
+		l.draw()
+		l.window.Update()
+
+		columns := l.columns()
+		switch {
+		case l.window.JustPressed(pixelgl.KeyEscape):
+			return ""
+		case l.window.JustPressed(pixelgl.KeyEnter) && len(l.roms) > 0:
+			return l.roms[l.selected].Path
+		case l.window.JustPressed(pixelgl.KeyLeft):
+			l.move(-1)
+		case l.window.JustPressed(pixelgl.KeyRight):
+			l.move(1)
+		case l.window.JustPressed(pixelgl.KeyUp):
+			l.move(-columns)
+		case l.window.JustPressed(pixelgl.KeyDown):
+			l.move(columns)
+		case l.window.JustPressed(pixelgl.MouseButtonLeft):
+			if i := l.cardAt(l.window.MousePosition()); i >= 0 {
+				return l.roms[i].Path
+			}
+		}
+		l.scrollBy(-l.window.MouseScroll().Y * cardHeight / 4)
+	}
+	return ""
+}
+
+func (l *launcher) columns() int {
+	return int(math.Max(1, math.Floor((l.window.Bounds().W()-20)/cardWidth)))
+}
+
+// card returns the rectangle of the card of a ROM, in the coordinates of the window.
+func (l *launcher) card(i int) pixel.Rect {
+	columns := l.columns()
+	x := 20 + float64(i%columns)*cardWidth
+	top := l.window.Bounds().H() - 40 - float64(i/columns)*cardHeight + l.scroll
+	return pixel.R(x, top-cardHeight+10, x+cardWidth-10, top)
+}
+
+func (l *launcher) cardAt(p pixel.Vec) int {
+	for i := range l.roms {
+		if l.card(i).Contains(p) {
+			return i
+		}
+	}
+	return -1
+}
+
+// move selects another ROM and scrolls to it.
+func (l *launcher) move(by int) {
+	if i := l.selected + by; i >= 0 && i < len(l.roms) {
+		l.selected = i
+	}
+	card := l.card(l.selected)
+	if card.Max.Y > l.window.Bounds().H()-30 {
+		l.scrollBy(l.window.Bounds().H() - 30 - card.Max.Y)
+	}
+	if card.Min.Y < 0 {
+		l.scrollBy(-card.Min.Y)
+	}
+}
+
+func (l *launcher) scrollBy(dy float64) {
+	rows := (len(l.roms) + l.columns() - 1) / l.columns()
+	max := math.Max(0, float64(rows*cardHeight)-(l.window.Bounds().H()-40))
+	l.scroll = math.Max(0, math.Min(max, l.scroll+dy))
+}
+
+func (l *launcher) draw() {
+	l.window.Clear(l.theme.Background)
+	txt := text.New(pixel.V(20, l.window.Bounds().H()-25), l.atlas)
+	txt.Color = l.theme.Foreground
+	if len(l.roms) == 0 {
+		fmt.Fprint(txt, "No ROM found")
+	} else {
+		fmt.Fprint(txt, "Arrows and Enter, or click, to run a ROM - Escape to quit")
+	}
+
+	frames := imdraw.New(nil)
+	for i, info := range l.roms {
+		card := l.card(i)
+		if card.Min.Y > l.window.Bounds().H() || card.Max.Y < 0 {
+			continue
+		}
+		frames.Color = l.theme.Grid()
+		if i == l.selected {
+			frames.Color = l.theme.Foreground
+		}
+		frames.Push(card.Min, card.Max)
+		frames.Rectangle(2)
+
+		center := pixel.V(card.Center().X, card.Max.Y-10-chip8.DisplayHeight*thumbnailScale/2)
+		l.pictures[i].Draw(l.window, pixel.IM.Scaled(pixel.ZV, thumbnailScale).Moved(center))
+
+		recent := ""
+		if info.Recent {
+			recent = "  (recent)"
+		}
+		txt.Dot = pixel.V(card.Min.X+10, card.Min.Y+32)
+		txt.Orig = txt.Dot
+		fmt.Fprintf(txt, "%s%s\n%d bytes  %.8s", info.ROM.Name, recent, len(info.ROM.Data), info.Hash)
+	}
+	frames.Draw(l.window)
+	txt.Draw(l.window, pixel.IM)
+}
@@ -0,0 +1,212 @@
+package window
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/gemulation/chip8/chip8"
+)
+
+// menuItem is an entry of the pause menu.
+type menuItem int
+
+const (
+	menuResume menuItem = iota
+	menuAdvance
+	menuReset
+	menuSpeed
+	menuSound
+	menuQuit
+	menuItems
+)
+
+// label returns the text of an entry of the pause menu.
+func (w *Window) label(item menuItem) string {
+	switch item {
+	case menuResume:
+		return "Resume"
+	case menuAdvance:
+		return "Advance a frame"
+	case menuReset:
+		return "Reset"
+	case menuSpeed:
+		return fmt.Sprintf("< Speed: %d >", w.emulator.Speed())
+	case menuSound:
+		if w.emulator.Muted() {
+			return "Sound: off"
+		}
+		return "Sound: on"
+	}
+	return "Quit"
+}
+
+// hotkeys runs the controls of the emulation bound to keys.
+func (w *Window) hotkeys() {
+	switch {
+	case w.window.JustPressed(pixelgl.KeyEscape):
+		w.openMenu(!w.menu)
+	case w.window.JustPressed(pixelgl.KeyPause), w.window.JustPressed(pixelgl.KeyF6):
+		w.pause(!w.emulator.Paused())
+	case w.window.JustPressed(pixelgl.KeyF7):
+		w.advance()
+	case w.window.JustPressed(pixelgl.KeyF8):
+		w.emulator.Reset()
+	case w.window.JustPressed(pixelgl.KeyF9):
+		w.toggleSound()
+	case w.window.JustPressed(pixelgl.KeyPageUp):
+		w.changeSpeed(2)
+	case w.window.JustPressed(pixelgl.KeyPageDown):
+		w.changeSpeed(-2)
+	}
+}
+
+// openMenu shows or hides the pause menu, pausing the emulation meanwhile.
+// Hiding it pauses or resumes the emulation as it was when the menu was shown.
+func (w *Window) openMenu(open bool) {
+	if open && !w.menu {
+		w.paused = w.emulator.Paused()
+	}
+	w.menu = open
+	w.selected = menuResume
+	w.clicked = -1
+	w.emulator.Keypad().ReleaseAll()
+	w.pause(open || w.paused)
+}
+
+// updateMenu selects the entries of the pause menu with the arrows or the mouse.
+func (w *Window) updateMenu() {
+	switch {
+	case w.window.JustPressed(pixelgl.KeyEscape):
+		w.openMenu(false)
+	case w.window.JustPressed(pixelgl.KeyUp):
+		w.selected = (w.selected + menuItems - 1) % menuItems
+	case w.window.JustPressed(pixelgl.KeyDown):
+		w.selected = (w.selected + 1) % menuItems
+	case w.window.JustPressed(pixelgl.KeyLeft) && w.selected == menuSpeed:
+		w.changeSpeed(-2)
+	case w.window.JustPressed(pixelgl.KeyRight) && w.selected == menuSpeed:
+		w.changeSpeed(2)
+	case w.window.JustPressed(pixelgl.KeyEnter), w.window.JustPressed(pixelgl.KeySpace):
+		w.choose(w.selected)
+	case w.window.JustPressed(pixelgl.MouseButtonLeft):
+		for item, r := range w.items {
+			if r.Contains(w.window.MousePosition()) {
+				w.selected = menuItem(item)
+				w.choose(w.selected)
+			}
+		}
+	default:
+		return
+	}
+	w.display.SetDirty(true)
+}
+
+// choose runs an entry of the pause menu.
+func (w *Window) choose(item menuItem) {
+	switch item {
+	case menuResume:
+		w.openMenu(false)
+	case menuAdvance:
+		w.advance()
+	case menuReset:
+		w.emulator.Reset()
+	case menuSpeed:
+		w.changeSpeed(2)
+	case menuSound:
+		w.toggleSound()
+	case menuQuit:
+		w.quit = true
+	}
+}
+
+// pause pauses or resumes the emulation.
+func (w *Window) pause(paused bool) {
+	w.emulator.SetPaused(paused)
+	w.display.SetDirty(true)
+	w.title()
+}
+
+// advance pauses the emulation, then executes its next frame.
+func (w *Window) advance() {
+	if !w.emulator.Paused() {
+		w.pause(true)
+	}
+	w.emulator.Advance()
+}
+
+// changeSpeed multiplies the instructions per frame by a factor, dividing them when it is negative.
+func (w *Window) changeSpeed(factor int) {
+	speed := w.emulator.Speed() * factor
+	if factor < 0 {
+		speed = w.emulator.Speed() / -factor
+	}
+	if speed < 1 {
+		speed = 1
+	}
+	w.emulator.SetSpeed(speed)
+	w.title()
+}
+
+func (w *Window) toggleSound() {
+	w.emulator.SetMuted(!w.emulator.Muted())
+	w.title()
+}
+
+// title shows the name of the ROM in the title of the window, with the state of the emulation.
+func (w *Window) title() {
+	title := w.emulator.ROM().Name
+	if w.emulator.Paused() {
+		title += " - paused"
+	}
+	if speed := w.emulator.Speed(); speed != chip8.InstructionsPerFrame {
+		title += fmt.Sprintf(" - %d instructions per frame", speed)
+	}
+	if w.emulator.Muted() {
+		title += " - muted"
+	}
+	w.window.SetTitle(title)
+}
+
+// drawOverlay dims the screen while paused, with the pause menu when it is shown.
+func (w *Window) drawOverlay() {
+	theme := w.display.Theme()
+	dim := imdraw.New(nil)
+	dim.Color = color.RGBA{A: 0xA0}
+	dim.Push(w.screen.Min, w.screen.Max)
+	dim.Rectangle(0)
+	dim.Draw(w.window)
+
+	lines := []string{"Paused"}
+	if w.menu {
+		lines = append(lines, "")
+		for item := menuResume; item < menuItems; item++ {
+			lines = append(lines, w.label(item))
+		}
+	}
+	height := w.screen.H() / 12
+	if height > w.screen.W()/16 {
+		height = w.screen.W() / 16
+	}
+	scale := height / w.atlas.LineHeight()
+	top := w.screen.Center().Y + height*float64(len(lines))/2
+
+	txt := text.New(pixel.ZV, w.atlas)
+	for i, line := range lines {
+		txt.Clear()
+		txt.Color = theme.Foreground
+		if item := menuItem(i - 2); w.menu && i >= 2 && item != w.selected {
+			txt.Color = theme.Grid()
+		}
+		fmt.Fprint(txt, line)
+		center := pixel.V(w.screen.Center().X, top-height*(float64(i)+0.5))
+		txt.Draw(w.window, pixel.IM.Moved(center.Sub(txt.Bounds().Center())).Scaled(center, scale))
+		if i >= 2 {
+			size := pixel.V(txt.Bounds().W()*scale/2, height/2)
+			w.items[i-2] = pixel.Rect{Min: center.Sub(size), Max: center.Add(size)}
+		}
+	}
+}
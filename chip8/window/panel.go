@@ -0,0 +1,104 @@
+package window
+
+import (
+	"fmt"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/gemulation/chip8/chip8"
+)
+
+// panelMargin is the space around the keys of the panel, relative to their size.
+const panelMargin = 0.08
+
+// layout splits the window between the screen and the keypad panel, on the right of the screen.
+func (w *Window) layout(bounds pixel.Rect) (screen, panel pixel.Rect) {
+	if !w.Panel {
+		return bounds, pixel.Rect{}
+	}
+	side := bounds.W() / 3
+	if bounds.H() < side {
+		side = bounds.H()
+	}
+	screen = pixel.R(bounds.Min.X, bounds.Min.Y, bounds.Max.X-side, bounds.Max.Y)
+	center := bounds.Center().Y
+	panel = pixel.R(bounds.Max.X-side, center-side/2, bounds.Max.X, center+side/2)
+	return screen, panel
+}
+
+// panelKey returns the rectangle of the key at the given index of the keypad layout.
+func (w *Window) panelKey(index int) pixel.Rect {
+	size := w.panel.W() / 4
+	x := w.panel.Min.X + float64(index%4)*size
+	y := w.panel.Max.Y - float64(index/4+1)*size // the first row is at the top
+	margin := size * panelMargin
+	return pixel.R(x+margin, y+margin, x+size-margin, y+size-margin)
+}
+
+// panelKeyAt returns the key of the keypad under a point, or -1.
+func (w *Window) panelKeyAt(p pixel.Vec) int {
+	if !w.Panel {
+		return -1
+	}
+	for i, key := range chip8.KeypadLayout {
+		if w.panelKey(i).Contains(p) {
+			return key
+		}
+	}
+	return -1
+}
+
+// click presses the key of the panel under the mouse while its button is held.
+func (w *Window) click() {
+	if w.window.JustPressed(pixelgl.MouseButtonLeft) {
+		w.clicked = w.panelKeyAt(w.window.MousePosition())
+		w.emulator.Keypad().Press(w.clicked)
+	}
+	if w.window.JustReleased(pixelgl.MouseButtonLeft) {
+		w.unclick()
+	}
+}
+
+// unclick releases the key of the panel clicked, unless it is held on the keyboard.
+func (w *Window) unclick() {
+	if key := w.clicked; key >= 0 && !(w.bound[key] && w.window.Pressed(w.buttons[key])) {
+		w.emulator.Keypad().Release(key)
+	}
+	w.clicked = -1
+}
+
+// drawPanel draws the keypad, with the keys held highlighted and the keys of the keyboard mapped to them.
+func (w *Window) drawPanel() {
+	theme := w.display.Theme()
+	keys := imdraw.New(nil)
+	labels := text.New(pixel.ZV, w.atlas)
+	size := w.panel.W() / 4 * (1 - 2*panelMargin)
+
+	for i, key := range chip8.KeypadLayout {
+		r := w.panelKey(i)
+		face, ink := theme.Grid(), theme.Foreground
+		if w.held[key] {
+			face, ink = theme.Foreground, theme.Background
+		}
+		keys.Color = face
+		keys.Push(r.Min, r.Max)
+		keys.Rectangle(0)
+
+		// the hex digit in the middle, the key of the keyboard below it
+		labels.Clear()
+		labels.Color = ink
+		fmt.Fprintf(labels, "%X", key)
+		scale := size * 0.5 / labels.Bounds().H()
+		digit := labels.Bounds()
+		labels.Draw(w.window, pixel.IM.Moved(r.Center().Sub(digit.Center())).Scaled(r.Center(), scale))
+		if w.bound[key] {
+			labels.Clear()
+			fmt.Fprint(labels, w.emulator.Keymap().Keys[key])
+			below := pixel.V(r.Center().X, r.Min.Y+size*0.15)
+			labels.Draw(w.window, pixel.IM.Moved(below.Sub(labels.Bounds().Center())).Scaled(below, scale/3))
+		}
+	}
+	keys.Draw(w.window)
+}
@@ -0,0 +1,450 @@
+package window
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/faiface/mainthread"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/gemulation/chip8/chip8"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/pkg/errors"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Window is the frontend showing the display in a window.
+type Window struct {
+	Panel bool // show the keypad beside the screen, its keys can be clicked
+	HUD   bool // show the rates of the emulation and the registers over the screen
+	Debug bool // open the debugger in another window
+
+	config   pixelgl.WindowConfig
+	window   *pixelgl.Window
+	emulator *chip8.Emulator
+	display  *chip8.Display
+	names    map[pixelgl.Button]string // names of the keys of the keyboard, as in the keymaps
+	buttons  [chip8.KeyboardSize]pixelgl.Button
+	bound    [chip8.KeyboardSize]bool // the key of the keymap exists on the keyboard
+	atlas    *text.Atlas
+
+	canvas     *pixelgl.Canvas
+	bounds     pixel.Rect
+	screen     pixel.Rect               // part of the window showing the screen
+	panel      pixel.Rect               // part of the window showing the keypad
+	held       [chip8.KeyboardSize]bool // keys highlighted on the panel
+	clicked    int                      // key of the panel clicked, or -1
+	menu       bool                     // the pause menu is shown
+	paused     bool                     // the emulation was paused when the pause menu was shown
+	rebinding  bool                     // the keys of the keyboard are being rebound
+	selected   menuItem
+	items      [menuItems]pixel.Rect // entries of the pause menu, to be clicked
+	quit       bool
+	stats      hudStats
+	debugger   *debugger
+	scale      int
+	pixels     []uint8
+	brightness chip8.Brightness
+}
+
+// New returns a window showing the pixels of the screen as squares of DisplayScaleFactor pixels.
+func New() *Window {
+	config := pixelgl.WindowConfig{
+		Bounds: pixel.R(
+			0, 0,
+			chip8.DisplayWidth*chip8.DisplayScaleFactor,
+			chip8.DisplayHeight*chip8.DisplayScaleFactor,
+		),
+		Resizable: true,
+		VSync:     true,
+	}
+	return &Window{config: config, clicked: -1}
+}
+
+// SetScale sets the initial size of the pixels of the screen in the window, DisplayScaleFactor by default.
+func (w *Window) SetScale(scale int) {
+	if scale <= 0 {
+		scale = chip8.DisplayScaleFactor
+	}
+	w.config.Bounds = pixel.R(0, 0, float64(chip8.DisplayWidth*scale), float64(chip8.DisplayHeight*scale))
+}
+
+// Run emulates the program in a window until it ends or the window is closed.
+// The instructions of a frame are executed between two vertical syncs of the display.
+func Run(emulator *chip8.Emulator) {
+	New().Run(emulator)
+}
+
+// Run emulates the program in the window until it ends or the window is closed.
+func (w *Window) Run(emulator *chip8.Emulator) {
+	pixelgl.Run(func() {
+		if err := emulator.RunFrontend(w); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// Open opens the window. It must be called from the function given to pixelgl.Run.
+func (w *Window) Open(emulator *chip8.Emulator) error {
+	window, err := pixelgl.NewWindow(w.config)
+	if err != nil {
+		return errors.Wrap(err, "failed to open window")
+	}
+	w.window = window
+	w.emulator = emulator
+	w.listen()
+	w.title()
+	w.display = emulator.Display()
+	w.display.SetDirty(true)
+	w.names = keyboardNames()
+	w.atlas = text.NewAtlas(basicfont.Face7x13, text.ASCII)
+	w.bind(emulator.Keymap())
+	if w.Debug {
+		return w.debug(true)
+	}
+	return nil
+}
+
+// Close closes the window.
+func (w *Window) Close() error {
+	w.debug(false)
+	w.window.Destroy()
+	return nil
+}
+
+// debug opens or closes the debugger.
+func (w *Window) debug(open bool) error {
+	w.Debug = open
+	if !open && w.debugger != nil {
+		w.debugger.close()
+		w.debugger = nil
+	}
+	if open && w.debugger == nil {
+		debugger, err := newDebugger(w.emulator)
+		if err != nil {
+			return err
+		}
+		w.debugger = debugger
+	}
+	return nil
+}
+
+// Update draws the screen when it changed, then waits for the vertical sync and polls the input.
+func (w *Window) Update() bool {
+	var brightness chip8.Brightness
+	w.display.Filter(&brightness)
+	if brightness != w.brightness {
+		w.brightness = brightness
+		w.display.SetDirty(true)
+	}
+
+	bounds := w.window.Bounds()
+	if bounds != w.bounds {
+		w.resize(bounds)
+	}
+	if w.Panel {
+		held := w.held
+		for i := range held {
+			held[i] = w.emulator.Keypad().Down(i)
+		}
+		if held != w.held {
+			w.held = held
+			w.display.SetDirty(true)
+		}
+	}
+	if w.HUD {
+		w.stats.measure(w.emulator)
+		w.display.SetDirty(true) // the registers change on every frame
+	}
+	if w.display.Dirty() {
+		w.upload()
+		w.window.Clear(colornames.Black)
+		w.canvas.Draw(w.window, pixel.IM.Moved(w.screen.Center()))
+		if w.Panel {
+			w.drawPanel()
+		}
+		if w.HUD {
+			w.drawHUD()
+		}
+		if w.emulator.Paused() {
+			w.drawOverlay()
+		}
+		w.display.SetDirty(false)
+	}
+	w.window.Update()
+	if w.debugger != nil && !w.debugger.update() {
+		w.debug(false)
+	}
+
+	if w.menu {
+		w.updateMenu()
+		return !w.window.Closed() && !w.quit
+	}
+	// the keys clicked on the panel are pressed like the keys of the keyboard
+	w.click()
+	w.hotkeys()
+	if w.window.JustPressed(pixelgl.KeyF1) {
+		report(w.debug(!w.Debug))
+	}
+	if w.window.JustPressed(pixelgl.KeyF2) {
+		w.display.NextTheme()
+	}
+	if w.window.JustPressed(pixelgl.KeyF3) {
+		w.display.NextPixelStyle()
+	}
+	if w.window.JustPressed(pixelgl.KeyF4) {
+		w.rebind()
+	}
+	if w.window.JustPressed(pixelgl.KeyF5) {
+		w.Panel = !w.Panel
+		w.unclick()
+		w.resize(bounds)
+	}
+	if w.window.JustPressed(pixelgl.KeyF10) {
+		w.HUD = !w.HUD
+		w.display.SetDirty(true)
+	}
+	if w.window.JustPressed(pixelgl.KeyF11) {
+		w.emulator.ToggleRecording()
+	}
+	if w.window.JustPressed(pixelgl.KeyF12) {
+		w.emulator.Screenshot()
+	}
+	return !w.window.Closed() && !w.quit
+}
+
+// listen feeds the keypad with the presses and releases of the keys of the keyboard as the window receives them,
+// rather than with the keys held at every frame, so the keys tapped within a frame are seen.
+// It must be called while the window is the current context, after it is opened.
+func (w *Window) listen() {
+	mainthread.Call(func() {
+		window := glfw.GetCurrentContext()
+		var previous glfw.KeyCallback // pixelgl's, for the keys held and the hotkeys
+		previous = window.SetKeyCallback(func(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+			if previous != nil {
+				previous(window, key, scancode, action, mods)
+			}
+			w.key(pixelgl.Button(key), action)
+		})
+	})
+}
+
+// key presses or releases the key of the keypad bound to a key of the keyboard.
+// The keyboard drives the pause menu and the rebinding instead while they are shown.
+func (w *Window) key(button pixelgl.Button, action glfw.Action) {
+	if w.menu || w.rebinding {
+		return
+	}
+	for i := range w.buttons {
+		if !w.bound[i] || w.buttons[i] != button {
+			continue
+		}
+		switch {
+		case action == glfw.Press:
+			w.emulator.Keypad().Press(i)
+		case action == glfw.Release && w.clicked != i: // still held on the panel
+			w.emulator.Keypad().Release(i)
+		}
+	}
+}
+
+// resize scales the screen by the largest integer factor that fits in the window.
+// The canvas is as large as the scaled screen so the pixel styles are drawn at the resolution of the window.
+func (w *Window) resize(bounds pixel.Rect) {
+	w.screen, w.panel = w.layout(bounds)
+	scale := int(math.Max(1, math.Min(
+		math.Floor(w.screen.W()/chip8.DisplayWidth),
+		math.Floor(w.screen.H()/chip8.DisplayHeight),
+	)))
+	if scale != w.scale {
+		w.scale = scale
+		w.canvas = pixelgl.NewCanvas(pixel.R(0, 0, float64(chip8.DisplayWidth*scale), float64(chip8.DisplayHeight*scale)))
+		w.pixels = make([]uint8, chip8.DisplayWidth*scale*chip8.DisplayHeight*scale*4)
+	}
+	w.bounds = bounds
+	w.display.SetDirty(true)
+}
+
+// upload draws the filtered memory into the texture of the canvas.
+func (w *Window) upload() {
+	theme := w.display.Theme()
+	grid := theme.Grid()
+	size := w.scale
+	stride := chip8.DisplayWidth * size
+
+	// shape of a pixel, the same for every pixel of the screen
+	fill := make([]bool, size*size)
+	line := make([]bool, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			fill[y*size+x], line[y*size+x] = w.display.PixelStyle().Shade(x, y, size)
+		}
+	}
+
+	for y := 0; y < chip8.DisplayHeight; y++ {
+		for x := 0; x < chip8.DisplayWidth; x++ {
+			lit := chip8.MixRGBA(theme.Background, theme.Foreground, w.brightness[y*chip8.DisplayWidth+x])
+			for py := 0; py < size; py++ {
+				// the rows of the texture go from the bottom to the top
+				row := (chip8.DisplayHeight-y)*size - 1 - py
+				for px := 0; px < size; px++ {
+					c := theme.Background
+					switch {
+					case line[py*size+px]:
+						c = grid
+					case fill[py*size+px]:
+						c = lit
+					}
+					i := (row*stride + x*size + px) * 4
+					setRGBA(w.pixels[i:i+4], c)
+				}
+			}
+		}
+	}
+	w.canvas.SetPixels(w.pixels)
+}
+
+// report prints the errors which don't stop the emulator.
+func report(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func setRGBA(pixel []uint8, c color.RGBA) {
+	pixel[0], pixel[1], pixel[2], pixel[3] = c.R, c.G, c.B, c.A
+}
+
+// bind maps the keys of the keypad to the keys of the keyboard with the names of the keymap.
+// The keys held are released, their buttons may not be bound anymore.
+func (w *Window) bind(keymap chip8.Keymap) {
+	w.emulator.Keypad().ReleaseAll()
+	w.clicked = -1
+	for i, name := range keymap.Keys {
+		w.bound[i] = false
+		for button, n := range w.names {
+			if n == name {
+				w.buttons[i], w.bound[i] = button, true
+			}
+		}
+		if !w.bound[i] {
+			report(errors.Errorf("no key %q on the keyboard for the key %X of the keypad", name, i))
+		}
+	}
+}
+
+// rebind shows the screen rebinding the keys of the keypad, in the order of the keypad,
+// each one to the next key pressed on the keyboard. The emulation is paused meanwhile, Escape cancels.
+func (w *Window) rebind() {
+	w.rebinding = true
+	defer func() { w.rebinding = false }()
+	var keymap chip8.Keymap
+	for done := 0; done < chip8.KeyboardSize; {
+		w.drawRebind(keymap, done)
+		w.window.Update()
+		if w.window.Closed() || w.window.JustPressed(pixelgl.KeyEscape) {
+			w.display.SetDirty(true)
+			return
+		}
+	next:
+		for button, name := range w.names {
+			if !w.window.JustPressed(button) {
+				continue
+			}
+			for _, key := range chip8.KeypadLayout[:done] {
+				if keymap.Keys[key] == name {
+					continue next // already bound
+				}
+			}
+			keymap.Keys[chip8.KeypadLayout[done]] = name
+			done++
+			break
+		}
+	}
+
+	keymap.Name = "custom"
+	for _, k := range chip8.Keymaps {
+		if k.Keys == keymap.Keys {
+			keymap.Name = k.Name
+		}
+	}
+	w.emulator.Rebind(keymap)
+	w.bind(keymap)
+	w.display.SetDirty(true)
+}
+
+// drawRebind draws the keypad with the keys bound so far, and the keys of the current keymap for the others.
+func (w *Window) drawRebind(keymap chip8.Keymap, done int) {
+	theme := w.display.Theme()
+	txt := text.New(pixel.ZV, w.atlas)
+	txt.Color = theme.Foreground
+	fmt.Fprintf(txt, "Rebinding the keys of %s\n\n", w.emulator.ROM().Name)
+	for i, key := range chip8.KeypadLayout {
+		name := w.emulator.Keymap().Keys[key]
+		switch {
+		case i < done:
+			name = keymap.Keys[key]
+		case i == done:
+			name = "?"
+		}
+		fmt.Fprintf(txt, "  %X: %-8s", key, name)
+		if i%4 == 3 {
+			fmt.Fprint(txt, "\n\n")
+		}
+	}
+	fmt.Fprintf(txt, "Press the key for %X, Escape to cancel", chip8.KeypadLayout[done])
+
+	bounds := w.window.Bounds()
+	scale := math.Max(1, math.Floor(math.Min(bounds.W()*0.9/txt.Bounds().W(), bounds.H()*0.9/txt.Bounds().H())))
+	w.window.Clear(theme.Background)
+	txt.Draw(w.window, pixel.IM.Moved(bounds.Center().Sub(txt.Bounds().Center())).Scaled(bounds.Center(), scale))
+}
+
+// pixelglKeypad are the names of the keys of the numeric keypad.
+var pixelglKeypad = map[pixelgl.Button]string{
+	pixelgl.KeyKP0: "KP0", pixelgl.KeyKP1: "KP1", pixelgl.KeyKP2: "KP2", pixelgl.KeyKP3: "KP3", pixelgl.KeyKP4: "KP4",
+	pixelgl.KeyKP5: "KP5", pixelgl.KeyKP6: "KP6", pixelgl.KeyKP7: "KP7", pixelgl.KeyKP8: "KP8", pixelgl.KeyKP9: "KP9",
+	pixelgl.KeyKPDivide: "KP/", pixelgl.KeyKPMultiply: "KP*", pixelgl.KeyKPSubtract: "KP-", pixelgl.KeyKPAdd: "KP+",
+	pixelgl.KeyKPDecimal: "KP.", pixelgl.KeyKPEnter: "KPEnter",
+}
+
+// keyboardNames returns the names of the keys of the keyboard which can be mapped,
+// after what is printed on them with the layout of the user.
+func keyboardNames() map[pixelgl.Button]string {
+	var names map[pixelgl.Button]string
+	mainthread.Call(func() {
+		names = layoutNames(func(button pixelgl.Button) string {
+			return glfw.GetKeyName(glfw.Key(button), 0)
+		})
+	})
+	return names
+}
+
+// layoutNames returns the names of the keys of the keyboard which can be mapped, given what a layout prints on them.
+// The number row is named after its digits whatever the layout prints on it, such as & é " ' on AZERTY,
+// so the keymaps find it on every layout.
+func layoutNames(printed func(pixelgl.Button) string) map[pixelgl.Button]string {
+	names := make(map[pixelgl.Button]string)
+	for button, name := range pixelglKeypad {
+		names[button] = name
+	}
+	// the printable keys are before Escape
+	for button := pixelgl.KeySpace; button < pixelgl.KeyEscape; button++ {
+		if button.String() == "Invalid" {
+			continue
+		}
+		name := strings.ToUpper(printed(button))
+		if name == "" && len(button.String()) == 1 || button >= pixelgl.Key0 && button <= pixelgl.Key9 {
+			name = button.String() // the position of the key on a US keyboard
+		}
+		if chip8.IsKeyName(name) {
+			names[button] = name
+		}
+	}
+	return names
+}
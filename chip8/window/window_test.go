@@ -0,0 +1,56 @@
+package window
+
+import (
+	"testing"
+
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/gemulation/chip8/chip8"
+	"github.com/stretchr/testify/require"
+)
+
+// layouts are what the layouts of the builtin keymaps print on the keys, where they differ from a US keyboard.
+var layouts = map[string]map[pixelgl.Button]string{
+	"qwerty": {},
+	"azerty": {
+		pixelgl.Key1: "&", pixelgl.Key2: "é", pixelgl.Key3: "\"", pixelgl.Key4: "'", pixelgl.Key5: "(",
+		pixelgl.Key6: "-", pixelgl.Key7: "è", pixelgl.Key8: "_", pixelgl.Key9: "ç", pixelgl.Key0: "à",
+		pixelgl.KeyMinus: ")", pixelgl.KeyQ: "a", pixelgl.KeyW: "z", pixelgl.KeyLeftBracket: "^", pixelgl.KeyRightBracket: "$",
+		pixelgl.KeyA: "q", pixelgl.KeySemicolon: "m", pixelgl.KeyApostrophe: "ù", pixelgl.KeyZ: "w",
+		pixelgl.KeyM: ",", pixelgl.KeyComma: ";", pixelgl.KeyPeriod: ":", pixelgl.KeySlash: "!",
+	},
+	"dvorak": {
+		pixelgl.KeyMinus: "[", pixelgl.KeyEqual: "]",
+		pixelgl.KeyQ: "'", pixelgl.KeyW: ",", pixelgl.KeyE: ".", pixelgl.KeyR: "p", pixelgl.KeyT: "y", pixelgl.KeyY: "f",
+		pixelgl.KeyU: "g", pixelgl.KeyI: "c", pixelgl.KeyO: "r", pixelgl.KeyP: "l", pixelgl.KeyLeftBracket: "/",
+		pixelgl.KeyRightBracket: "=", pixelgl.KeyS: "o", pixelgl.KeyD: "e", pixelgl.KeyF: "u", pixelgl.KeyG: "i",
+		pixelgl.KeyH: "d", pixelgl.KeyJ: "h", pixelgl.KeyK: "t", pixelgl.KeyL: "n", pixelgl.KeySemicolon: "s",
+		pixelgl.KeyApostrophe: "-", pixelgl.KeyZ: ";", pixelgl.KeyX: "q", pixelgl.KeyC: "j", pixelgl.KeyV: "k",
+		pixelgl.KeyB: "x", pixelgl.KeyN: "b", pixelgl.KeyComma: "w", pixelgl.KeyPeriod: "v", pixelgl.KeySlash: "z",
+	},
+	"numpad": {},
+}
+
+func TestLayoutNames(t *testing.T) {
+	for _, keymap := range chip8.Keymaps {
+		layout, ok := layouts[keymap.Name]
+		require.True(t, ok, keymap.Name)
+		names := layoutNames(func(button pixelgl.Button) string {
+			if printed, ok := layout[button]; ok {
+				return printed
+			}
+			if name := button.String(); len(name) == 1 {
+				return name
+			}
+			return ""
+		})
+		for key, name := range keymap.Keys {
+			found := 0
+			for _, n := range names {
+				if n == name {
+					found++
+				}
+			}
+			require.Equal(t, 1, found, "%s: key %X named %q", keymap.Name, key, name)
+		}
+	}
+}
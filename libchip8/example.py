@@ -0,0 +1,70 @@
+#!/usr/bin/env python3
+"""Runs a ROM with libchip8 and prints the screen and the registers.
+
+    $ go generate ./libchip8
+    $ python3 libchip8/example.py roms/brix.rom
+"""
+import ctypes
+import os
+import sys
+
+WIDTH, HEIGHT = 64, 32
+I, PC, SP, DT, ST = 16, 17, 18, 19, 20
+
+lib = ctypes.CDLL(os.path.join(os.path.dirname(os.path.abspath(__file__)), "libchip8.so"))
+lib.chip8_error.restype = ctypes.c_char_p
+lib.chip8_load_rom.argtypes = [ctypes.c_int, ctypes.c_char_p, ctypes.c_size_t]
+lib.chip8_framebuffer.argtypes = [ctypes.c_int, ctypes.c_char_p, ctypes.c_size_t]
+lib.chip8_save_state.argtypes = [ctypes.c_int, ctypes.c_char_p, ctypes.c_size_t]
+lib.chip8_load_state.argtypes = [ctypes.c_int, ctypes.c_char_p, ctypes.c_size_t]
+
+
+def check(h, result):
+    if result < 0:
+        raise RuntimeError(lib.chip8_error(h).decode())
+    return result
+
+
+def screen(h):
+    pixels = ctypes.create_string_buffer(WIDTH * HEIGHT)
+    check(h, lib.chip8_framebuffer(h, pixels, len(pixels)))
+    return "\n".join(
+        "".join("#" if pixels.raw[y * WIDTH + x] else "." for x in range(WIDTH))
+        for y in range(HEIGHT))
+
+
+def save_state(h):
+    size = check(h, lib.chip8_save_state(h, None, 0))
+    state = ctypes.create_string_buffer(size)
+    check(h, lib.chip8_save_state(h, state, size))
+    return state.raw
+
+
+def main():
+    with open(sys.argv[1], "rb") as f:
+        rom = f.read()
+
+    h = lib.chip8_create()
+    try:
+        check(h, lib.chip8_load_rom(h, rom, len(rom)))
+        check(h, lib.chip8_run_frames(h, 60))
+        state = save_state(h)
+
+        # hold the key 4 for a second, then go back to the saved state
+        check(h, lib.chip8_set_key(h, 4, 1))
+        check(h, lib.chip8_run_frames(h, 60))
+        check(h, lib.chip8_set_key(h, 4, 0))
+        check(h, lib.chip8_load_state(h, state, len(state)))
+
+        print(screen(h))
+        print(" ".join("V%X=%02X" % (i, check(h, lib.chip8_get_register(h, i))) for i in range(16)))
+        print("I=%03X PC=%03X SP=%d DT=%d ST=%d" % tuple(
+            check(h, lib.chip8_get_register(h, r)) for r in (I, PC, SP, DT, ST)))
+        pc = check(h, lib.chip8_get_register(h, PC))
+        print("next instruction %02X%02X" % (check(h, lib.chip8_peek(h, pc)), check(h, lib.chip8_peek(h, pc + 1))))
+    finally:
+        lib.chip8_destroy(h)
+
+
+if __name__ == "__main__":
+    main()
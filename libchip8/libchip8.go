@@ -0,0 +1,308 @@
+// Command libchip8 is the emulator as a C library, for the programs in other languages driving it.
+// It is built as a shared object, along with its header libchip8.h generated by cgo, with
+//
+//	go generate ./libchip8
+//
+// The emulators are designated by handles. The functions returning an int return -1 on error,
+// and chip8_error returns the message of the last error of the emulator.
+package main
+
+//go:generate go build -buildmode=c-shared -o libchip8.so .
+
+/*
+#include <stddef.h>
+#include <stdlib.h>
+#include <string.h>
+#include <stdint.h>
+
+#define CHIP8_WIDTH 64
+#define CHIP8_HEIGHT 32
+#define CHIP8_RAM_SIZE 4096
+#define CHIP8_KEYS 16
+
+// Registers of chip8_get_register and chip8_set_register, V0 to VF being 0 to 15.
+enum chip8_register {
+	CHIP8_VF = 15,
+	CHIP8_I,
+	CHIP8_PC,
+	CHIP8_SP,
+	CHIP8_DT,
+	CHIP8_ST
+};
+*/
+import "C"
+
+import (
+	"bytes"
+	"sync"
+	"unsafe"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/pkg/errors"
+)
+
+// maxStateSize is the size of the largest state loaded, larger than any snapshot, within the int length of C.GoBytes.
+const maxStateSize = 1 << 20
+
+// handle is an emulator created by chip8_create.
+type handle struct {
+	emulator *chip8.Emulator
+	err      *C.char // message of the last error, freed on the next error
+}
+
+var (
+	mu      sync.Mutex
+	handles = make(map[C.int]*handle)
+	next    = C.int(1)
+)
+
+func main() {}
+
+// get returns the emulator of a handle, or nil.
+func get(h C.int) *handle {
+	mu.Lock()
+	defer mu.Unlock()
+	return handles[h]
+}
+
+// fail records the error of an emulator and returns -1.
+func (h *handle) fail(err error) C.int {
+	C.free(unsafe.Pointer(h.err))
+	h.err = C.CString(err.Error())
+	return -1
+}
+
+// stopped returns 0 when the program ended, or records its fault and returns -1.
+func (h *handle) stopped() C.int {
+	if err := h.emulator.Fault(); err != nil {
+		return h.fail(err)
+	}
+	return 0
+}
+
+//export chip8_create
+func chip8_create() C.int {
+	emulator := chip8.NewEmulator(&chip8.ROM{Name: "empty"})
+	emulator.SetTrace(nil)
+	emulator.Reset()
+
+	mu.Lock()
+	defer mu.Unlock()
+	h := next
+	next++
+	handles[h] = &handle{emulator: emulator}
+	return h
+}
+
+//export chip8_destroy
+func chip8_destroy(h C.int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if e, ok := handles[h]; ok {
+		C.free(unsafe.Pointer(e.err))
+		delete(handles, h)
+	}
+}
+
+//export chip8_error
+func chip8_error(h C.int) *C.char {
+	if e := get(h); e != nil {
+		return e.err
+	}
+	return nil
+}
+
+//export chip8_load_rom
+func chip8_load_rom(h C.int, data *C.uint8_t, size C.size_t) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	if size > chip8.RamSize-chip8.ProgramLocation {
+		return e.fail(errors.Errorf("ROM of %d bytes too large", size))
+	}
+	if data == nil && size > 0 {
+		return e.fail(errors.New("ROM without data"))
+	}
+	rom := &chip8.ROM{Name: "rom", Data: C.GoBytes(unsafe.Pointer(data), C.int(size))}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	e.emulator = emulator
+	return 0
+}
+
+//export chip8_reset
+func chip8_reset(h C.int) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	e.emulator.Reset()
+	return 0
+}
+
+// chip8_step executes instructions without ticking the timers.
+// It returns 1 while the program runs, 0 when it ended, -1 when it faulted.
+//
+//export chip8_step
+func chip8_step(h C.int, cycles C.int) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	for ; cycles > 0; cycles-- {
+		if !e.emulator.Step() {
+			return e.stopped()
+		}
+	}
+	return 1
+}
+
+// chip8_run_frames executes the instructions of frames and ticks the timers after each of them.
+// It returns 1 while the program runs, 0 when it ended, -1 when it faulted.
+//
+//export chip8_run_frames
+func chip8_run_frames(h C.int, frames C.int) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	for ; frames > 0; frames-- {
+		if !e.emulator.Frame() {
+			return e.stopped()
+		}
+	}
+	return 1
+}
+
+//export chip8_peek
+func chip8_peek(h C.int, addr C.int) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	if addr < 0 || addr >= chip8.RamSize {
+		return e.fail(errors.Errorf("address %#x out of memory", int(addr)))
+	}
+	b, err := e.emulator.Peek(uint16(addr))
+	if err != nil {
+		return e.fail(err)
+	}
+	return C.int(b)
+}
+
+//export chip8_poke
+func chip8_poke(h C.int, addr C.int, value C.uint8_t) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	if addr < 0 || addr >= chip8.RamSize {
+		return e.fail(errors.Errorf("address %#x out of memory", int(addr)))
+	}
+	if err := e.emulator.Poke(uint16(addr), byte(value)); err != nil {
+		return e.fail(err)
+	}
+	return 0
+}
+
+// chip8_get_register returns the value of a register, numbered as chip8.Register.
+//
+//export chip8_get_register
+func chip8_get_register(h C.int, reg C.int) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	value, err := e.emulator.Register(chip8.Register(reg))
+	if err != nil {
+		return e.fail(err)
+	}
+	return C.int(value)
+}
+
+// chip8_set_register sets the value of a register, the values which don't fit it being errors.
+//
+//export chip8_set_register
+func chip8_set_register(h C.int, reg C.int, value C.int) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	if value < 0 || value > 0xFFFF {
+		return e.fail(errors.Errorf("value %d out of range", int(value)))
+	}
+	if err := e.emulator.SetRegister(chip8.Register(reg), uint16(value)); err != nil {
+		return e.fail(err)
+	}
+	return 0
+}
+
+// chip8_framebuffer copies the CHIP8_WIDTH * CHIP8_HEIGHT pixels of the screen, 1 when on, row by row.
+//
+//export chip8_framebuffer
+func chip8_framebuffer(h C.int, pixels *C.uint8_t, size C.size_t) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	screen := e.emulator.Screen()
+	if size < C.size_t(len(screen)) {
+		return e.fail(errors.Errorf("framebuffer of %d bytes too small", size))
+	}
+	copy((*[len(screen)]byte)(unsafe.Pointer(pixels))[:], screen[:])
+	return C.int(len(screen))
+}
+
+//export chip8_set_key
+func chip8_set_key(h C.int, key C.int, pressed C.int) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	if key < 0 || key >= chip8.KeyboardSize {
+		return e.fail(errors.Errorf("unknown key %d", int(key)))
+	}
+	e.emulator.SetKey(int(key), pressed != 0)
+	return 0
+}
+
+// chip8_save_state writes a snapshot of the machine into the buffer and returns its size.
+// When the buffer is too small, nothing is written and the size needed is returned.
+//
+//export chip8_save_state
+func chip8_save_state(h C.int, data *C.uint8_t, size C.size_t) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	var state bytes.Buffer
+	if err := e.emulator.SaveState(&state); err != nil {
+		return e.fail(err)
+	}
+	if C.size_t(state.Len()) <= size {
+		C.memcpy(unsafe.Pointer(data), unsafe.Pointer(&state.Bytes()[0]), C.size_t(state.Len()))
+	}
+	return C.int(state.Len())
+}
+
+// chip8_load_state restores a snapshot written by chip8_save_state.
+//
+//export chip8_load_state
+func chip8_load_state(h C.int, data *C.uint8_t, size C.size_t) C.int {
+	e := get(h)
+	if e == nil {
+		return -1
+	}
+	if data == nil || size == 0 {
+		return e.fail(errors.New("state without data"))
+	}
+	if size > maxStateSize {
+		return e.fail(errors.Errorf("state of %d bytes too large", size))
+	}
+	if err := e.emulator.LoadState(bytes.NewReader(C.GoBytes(unsafe.Pointer(data), C.int(size)))); err != nil {
+		return e.fail(err)
+	}
+	return 0
+}
@@ -0,0 +1,23 @@
+#include "libretro.h"
+
+// Go can't call C function pointers, these functions call the callbacks of the frontend for it.
+
+bool call_environment(retro_environment_t cb, unsigned cmd, void *data) {
+	return cb(cmd, data);
+}
+
+void call_video_refresh(retro_video_refresh_t cb, const void *data, unsigned width, unsigned height, size_t pitch) {
+	cb(data, width, height, pitch);
+}
+
+size_t call_audio_sample_batch(retro_audio_sample_batch_t cb, const int16_t *data, size_t frames) {
+	return cb(data, frames);
+}
+
+void call_input_poll(retro_input_poll_t cb) {
+	cb();
+}
+
+int16_t call_input_state(retro_input_state_t cb, unsigned port, unsigned device, unsigned index, unsigned id) {
+	return cb(port, device, index, id);
+}
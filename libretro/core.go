@@ -0,0 +1,404 @@
+// Command libretro is the emulator as a libretro core, to run in RetroArch and the other libretro frontends.
+// It is built as a shared object with
+//
+//	go build -buildmode=c-shared -o chip8_libretro.so ./libretro
+package main
+
+/*
+#include <stdlib.h>
+#include <string.h>
+#include "libretro.h"
+
+bool call_environment(retro_environment_t cb, unsigned cmd, void *data);
+void call_video_refresh(retro_video_refresh_t cb, const void *data, unsigned width, unsigned height, size_t pitch);
+size_t call_audio_sample_batch(retro_audio_sample_batch_t cb, const int16_t *data, size_t frames);
+void call_input_poll(retro_input_poll_t cb);
+int16_t call_input_state(retro_input_state_t cb, unsigned port, unsigned device, unsigned index, unsigned id);
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"path"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/gemulation/chip8/chip8"
+)
+
+// serializeSize is the size of the save states, larger than any encoded state.
+const serializeSize = 16 * 1024
+
+// keypad are the keys of the keypad pressed by the buttons of the RetroPad, by id.
+// The directions are on 2, 4, 6 and 8 where most games expect them, with 5 on A.
+var keypad = [16]int{
+	C.RETRO_DEVICE_ID_JOYPAD_B:      0x0,
+	C.RETRO_DEVICE_ID_JOYPAD_Y:      0x1,
+	C.RETRO_DEVICE_ID_JOYPAD_SELECT: 0xC,
+	C.RETRO_DEVICE_ID_JOYPAD_START:  0xD,
+	C.RETRO_DEVICE_ID_JOYPAD_UP:     0x2,
+	C.RETRO_DEVICE_ID_JOYPAD_DOWN:   0x8,
+	C.RETRO_DEVICE_ID_JOYPAD_LEFT:   0x4,
+	C.RETRO_DEVICE_ID_JOYPAD_RIGHT:  0x6,
+	C.RETRO_DEVICE_ID_JOYPAD_A:      0x5,
+	C.RETRO_DEVICE_ID_JOYPAD_X:      0x3,
+	C.RETRO_DEVICE_ID_JOYPAD_L:      0x7,
+	C.RETRO_DEVICE_ID_JOYPAD_R:      0x9,
+	C.RETRO_DEVICE_ID_JOYPAD_L2:     0xA,
+	C.RETRO_DEVICE_ID_JOYPAD_R2:     0xB,
+	C.RETRO_DEVICE_ID_JOYPAD_L3:     0xE,
+	C.RETRO_DEVICE_ID_JOYPAD_R3:     0xF,
+}
+
+// option is a core option, shown by the frontend with its description and its values, the first one being the default.
+type option struct {
+	key, description string
+	values           []string
+}
+
+// quirkOptions are the options enabling the quirks, with the names of ParseQuirks.
+var quirkOptions = []struct{ key, quirk, description string }{
+	{"chip8_quirk_shift", "shift", "Shift Vy into Vx (8xy6, 8xyE)"},
+	{"chip8_quirk_loadstore", "loadstore", "Increment I on load and store (Fx55, Fx65)"},
+	{"chip8_quirk_jump", "jump", "Jump to xnn + Vx (Bxnn)"},
+	{"chip8_quirk_clip", "clip", "Clip sprites at the edges"},
+	{"chip8_quirk_vfreset", "vfreset", "Reset VF on logic operations (8xy1, 8xy2, 8xy3)"},
+}
+
+func options() []option {
+	themes := make([]string, len(chip8.Themes))
+	for i, theme := range chip8.Themes {
+		themes[i] = theme.Name
+	}
+	opts := []option{
+		{"chip8_speed", "Instructions per frame", []string{
+			strconv.Itoa(chip8.InstructionsPerFrame), "6", "8", "10", "15", "20", "30", "50", "100", "200", "500", "1000",
+		}},
+		{"chip8_palette", "Palette", themes},
+	}
+	for _, q := range quirkOptions {
+		opts = append(opts, option{q.key, q.description, []string{"disabled", "enabled"}})
+	}
+	return opts
+}
+
+var (
+	environment      C.retro_environment_t
+	videoRefresh     C.retro_video_refresh_t
+	audioSampleBatch C.retro_audio_sample_batch_t
+	inputPoll        C.retro_input_poll_t
+	inputState       C.retro_input_state_t
+
+	emulator *chip8.Emulator
+	ended    bool // the program ended, the last frame is shown until the game is unloaded
+	theme    = chip8.Themes[0]
+	audio    = &audioBuffer{tone: chip8.NewTone(chip8.DefaultPitch, chip8.DefaultVolume)}
+	frame    [chip8.DisplayWidth * chip8.DisplayHeight]uint32
+
+	// C memory kept for the frontend
+	libraryName      = C.CString("CHIP-8")
+	libraryVersion   = C.CString("1.0")
+	validExtensions  = C.CString("ch8|rom|c8")
+	variables        = newVariables()
+	inputDescriptors = newInputDescriptors()
+)
+
+// audioBuffer collects the samples of a frame, in stereo.
+type audioBuffer struct {
+	tone    *chip8.Tone
+	mono    [chip8.SamplesPerTick]int16
+	samples []int16
+}
+
+// Tick generates the samples of a tick of the timers.
+func (a *audioBuffer) Tick(buzzer bool) error {
+	a.tone.Generate(a.mono[:], buzzer)
+	for _, s := range a.mono {
+		a.samples = append(a.samples, s, s)
+	}
+	return nil
+}
+
+// Close does nothing, the buffer lives as long as the core.
+func (a *audioBuffer) Close() error {
+	return nil
+}
+
+func main() {}
+
+//export retro_api_version
+func retro_api_version() C.uint {
+	return C.RETRO_API_VERSION
+}
+
+//export retro_set_environment
+func retro_set_environment(cb C.retro_environment_t) {
+	environment = cb
+	C.call_environment(cb, C.RETRO_ENVIRONMENT_SET_VARIABLES, unsafe.Pointer(variables))
+}
+
+//export retro_set_video_refresh
+func retro_set_video_refresh(cb C.retro_video_refresh_t) {
+	videoRefresh = cb
+}
+
+//export retro_set_audio_sample
+func retro_set_audio_sample(cb C.retro_audio_sample_t) {
+}
+
+//export retro_set_audio_sample_batch
+func retro_set_audio_sample_batch(cb C.retro_audio_sample_batch_t) {
+	audioSampleBatch = cb
+}
+
+//export retro_set_input_poll
+func retro_set_input_poll(cb C.retro_input_poll_t) {
+	inputPoll = cb
+}
+
+//export retro_set_input_state
+func retro_set_input_state(cb C.retro_input_state_t) {
+	inputState = cb
+}
+
+//export retro_init
+func retro_init() {
+}
+
+//export retro_deinit
+func retro_deinit() {
+}
+
+//export retro_get_system_info
+func retro_get_system_info(info *C.struct_retro_system_info) {
+	*info = C.struct_retro_system_info{
+		library_name:     libraryName,
+		library_version:  libraryVersion,
+		valid_extensions: validExtensions,
+		need_fullpath:    false,
+		block_extract:    false,
+	}
+}
+
+//export retro_get_system_av_info
+func retro_get_system_av_info(info *C.struct_retro_system_av_info) {
+	info.geometry = C.struct_retro_game_geometry{
+		base_width:   chip8.DisplayWidth,
+		base_height:  chip8.DisplayHeight,
+		max_width:    chip8.DisplayWidth,
+		max_height:   chip8.DisplayHeight,
+		aspect_ratio: chip8.DisplayWidth / chip8.DisplayHeight,
+	}
+	info.timing = C.struct_retro_system_timing{
+		fps:         chip8.TimerFrequency,
+		sample_rate: chip8.SampleRate,
+	}
+}
+
+//export retro_set_controller_port_device
+func retro_set_controller_port_device(port, device C.uint) {
+}
+
+//export retro_load_game
+func retro_load_game(game *C.struct_retro_game_info) C.bool {
+	if game == nil || game.data == nil {
+		return false
+	}
+	rom := &chip8.ROM{Name: "rom", Data: C.GoBytes(game.data, C.int(game.size))}
+	if game.path != nil {
+		rom.Name = path.Base(C.GoString(game.path))
+	}
+	if len(rom.Data) > chip8.RamSize-chip8.ProgramLocation {
+		return false
+	}
+
+	format := C.enum_retro_pixel_format(C.RETRO_PIXEL_FORMAT_XRGB8888)
+	if !C.call_environment(environment, C.RETRO_ENVIRONMENT_SET_PIXEL_FORMAT, unsafe.Pointer(&format)) {
+		return false
+	}
+	C.call_environment(environment, C.RETRO_ENVIRONMENT_SET_INPUT_DESCRIPTORS, unsafe.Pointer(inputDescriptors))
+
+	emulator = chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.SetAudioSink(audio)
+	emulator.Reset()
+	ended = false
+	updateOptions()
+	return true
+}
+
+//export retro_load_game_special
+func retro_load_game_special(gameType C.uint, info *C.struct_retro_game_info, numInfo C.size_t) C.bool {
+	return false
+}
+
+//export retro_unload_game
+func retro_unload_game() {
+	emulator = nil
+}
+
+//export retro_reset
+func retro_reset() {
+	if emulator != nil {
+		emulator.Reset()
+		ended = false
+	}
+}
+
+//export retro_get_region
+func retro_get_region() C.uint {
+	return C.RETRO_REGION_NTSC
+}
+
+//export retro_run
+func retro_run() {
+	if emulator == nil {
+		return
+	}
+	var updated C.bool
+	if C.call_environment(environment, C.RETRO_ENVIRONMENT_GET_VARIABLE_UPDATE, unsafe.Pointer(&updated)) && updated {
+		updateOptions()
+	}
+
+	C.call_input_poll(inputPoll)
+	for id, key := range keypad {
+		pressed := C.call_input_state(inputState, 0, C.RETRO_DEVICE_JOYPAD, 0, C.uint(id)) != 0
+		emulator.SetKey(key, pressed)
+	}
+
+	if !ended && !emulator.Frame() {
+		ended = true
+	}
+	if ended {
+		audio.Tick(false)
+	}
+
+	screen := emulator.Screen()
+	bg, fg := xrgb(theme.Background), xrgb(theme.Foreground)
+	for i, p := range screen {
+		frame[i] = bg
+		if p == 1 {
+			frame[i] = fg
+		}
+	}
+	C.call_video_refresh(videoRefresh, unsafe.Pointer(&frame[0]), chip8.DisplayWidth, chip8.DisplayHeight, chip8.DisplayWidth*4)
+
+	for sent := 0; sent < len(audio.samples); {
+		n := C.call_audio_sample_batch(audioSampleBatch, (*C.int16_t)(unsafe.Pointer(&audio.samples[sent])), C.size_t(len(audio.samples)-sent)/2)
+		if n == 0 {
+			break
+		}
+		sent += int(n) * 2
+	}
+	audio.samples = audio.samples[:0]
+}
+
+//export retro_serialize_size
+func retro_serialize_size() C.size_t {
+	return serializeSize
+}
+
+//export retro_serialize
+func retro_serialize(data unsafe.Pointer, size C.size_t) C.bool {
+	var state bytes.Buffer
+	if emulator == nil || data == nil || emulator.SaveState(&state) != nil || state.Len() > int(size) {
+		return false
+	}
+	C.memcpy(data, unsafe.Pointer(&state.Bytes()[0]), C.size_t(state.Len()))
+	return true
+}
+
+//export retro_unserialize
+func retro_unserialize(data unsafe.Pointer, size C.size_t) C.bool {
+	if emulator == nil || data == nil || size == 0 || size > serializeSize {
+		return false
+	}
+	if emulator.LoadState(bytes.NewReader(C.GoBytes(data, C.int(size)))) != nil {
+		return false
+	}
+	ended = false
+	return true
+}
+
+//export retro_cheat_reset
+func retro_cheat_reset() {
+}
+
+//export retro_cheat_set
+func retro_cheat_set(index C.uint, enabled C.bool, code *C.char) {
+}
+
+//export retro_get_memory_data
+func retro_get_memory_data(id C.uint) unsafe.Pointer {
+	return nil
+}
+
+//export retro_get_memory_size
+func retro_get_memory_size(id C.uint) C.size_t {
+	return 0
+}
+
+// updateOptions applies the core options set in the frontend.
+func updateOptions() {
+	if speed, err := strconv.Atoi(variable("chip8_speed")); err == nil {
+		emulator.SetSpeed(speed)
+	}
+	for _, t := range chip8.Themes {
+		if t.Name == variable("chip8_palette") {
+			theme = t
+		}
+	}
+	var names []string
+	for _, q := range quirkOptions {
+		if variable(q.key) == "enabled" {
+			names = append(names, q.quirk)
+		}
+	}
+	if quirks, err := chip8.ParseQuirks(strings.Join(names, ",")); err == nil {
+		emulator.SetQuirks(quirks)
+	}
+}
+
+// variable returns the value of a core option, or "" when the frontend doesn't know it.
+func variable(key string) string {
+	v := C.struct_retro_variable{key: C.CString(key)}
+	defer C.free(unsafe.Pointer(v.key))
+	if !C.call_environment(environment, C.RETRO_ENVIRONMENT_GET_VARIABLE, unsafe.Pointer(&v)) || v.value == nil {
+		return ""
+	}
+	return C.GoString(v.value)
+}
+
+// newVariables returns the core options in C memory, terminated by an empty variable.
+func newVariables() *C.struct_retro_variable {
+	opts := options()
+	size := C.size_t(unsafe.Sizeof(C.struct_retro_variable{}))
+	array := C.calloc(C.size_t(len(opts)+1), size)
+	for i, opt := range opts {
+		v := (*C.struct_retro_variable)(unsafe.Pointer(uintptr(array) + uintptr(i)*uintptr(size)))
+		v.key = C.CString(opt.key)
+		v.value = C.CString(opt.description + "; " + strings.Join(opt.values, "|"))
+	}
+	return (*C.struct_retro_variable)(array)
+}
+
+// newInputDescriptors returns the keys of the keypad pressed by the buttons in C memory, terminated by an empty descriptor.
+func newInputDescriptors() *C.struct_retro_input_descriptor {
+	size := C.size_t(unsafe.Sizeof(C.struct_retro_input_descriptor{}))
+	array := C.calloc(C.size_t(len(keypad)+1), size)
+	for id, key := range keypad {
+		d := (*C.struct_retro_input_descriptor)(unsafe.Pointer(uintptr(array) + uintptr(id)*uintptr(size)))
+		d.device = C.RETRO_DEVICE_JOYPAD
+		d.id = C.uint(id)
+		d.description = C.CString(fmt.Sprintf("Key %X", key))
+	}
+	return (*C.struct_retro_input_descriptor)(array)
+}
+
+// xrgb returns a color in the XRGB8888 format.
+func xrgb(c color.RGBA) uint32 {
+	return uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+}
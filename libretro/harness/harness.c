@@ -0,0 +1,200 @@
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+#include "harness.h"
+
+// The frontend side of the libretro API: the functions of the core and the state shown to it.
+// Cores can't be unloaded, the Go runtime of the core can't be stopped.
+
+static void *handle;
+static const char *error;
+
+static void (*core_init)(void);
+static void (*core_deinit)(void);
+static void (*core_set_environment)(retro_environment_t);
+static void (*core_set_video_refresh)(retro_video_refresh_t);
+static void (*core_set_audio_sample)(retro_audio_sample_t);
+static void (*core_set_audio_sample_batch)(retro_audio_sample_batch_t);
+static void (*core_set_input_poll)(retro_input_poll_t);
+static void (*core_set_input_state)(retro_input_state_t);
+static void (*core_get_system_av_info)(struct retro_system_av_info *);
+static bool (*core_load_game)(const struct retro_game_info *);
+static void (*core_unload_game)(void);
+static void (*core_run)(void);
+static size_t (*core_serialize_size)(void);
+static bool (*core_serialize)(void *, size_t);
+static bool (*core_unserialize)(const void *, size_t);
+
+static uint32_t frame[HARNESS_MAX_PIXELS];
+static unsigned frame_width, frame_height;
+static size_t audio_frames;
+static uint16_t buttons;
+
+static char *options[HARNESS_MAX_OPTIONS][2];
+static int num_options;
+static bool options_updated;
+
+static bool environment(unsigned cmd, void *data) {
+	switch (cmd) {
+	case RETRO_ENVIRONMENT_SET_PIXEL_FORMAT:
+		return *(enum retro_pixel_format *)data == RETRO_PIXEL_FORMAT_XRGB8888;
+	case RETRO_ENVIRONMENT_SET_INPUT_DESCRIPTORS:
+		return true;
+	case RETRO_ENVIRONMENT_SET_VARIABLES:
+		for (const struct retro_variable *v = data; v->key && num_options < HARNESS_MAX_OPTIONS; v++) {
+			// the default value is the first one after the description
+			const char *values = strstr(v->value, "; ");
+			values = values ? values + 2 : v->value;
+			options[num_options][0] = strdup(v->key);
+			options[num_options][1] = strndup(values, strcspn(values, "|"));
+			num_options++;
+		}
+		return true;
+	case RETRO_ENVIRONMENT_GET_VARIABLE: {
+		struct retro_variable *v = data;
+		for (int i = 0; i < num_options; i++) {
+			if (strcmp(options[i][0], v->key) == 0) {
+				v->value = options[i][1];
+				return true;
+			}
+		}
+		v->value = NULL;
+		return false;
+	}
+	case RETRO_ENVIRONMENT_GET_VARIABLE_UPDATE:
+		*(bool *)data = options_updated;
+		options_updated = false;
+		return true;
+	}
+	return false;
+}
+
+static void video_refresh(const void *data, unsigned width, unsigned height, size_t pitch) {
+	if (!data || width * height > HARNESS_MAX_PIXELS) {
+		return;
+	}
+	for (unsigned y = 0; y < height; y++) {
+		memcpy(&frame[y * width], (const char *)data + y * pitch, width * sizeof(uint32_t));
+	}
+	frame_width = width;
+	frame_height = height;
+}
+
+static void audio_sample(int16_t left, int16_t right) {
+	audio_frames++;
+}
+
+static size_t audio_sample_batch(const int16_t *data, size_t frames) {
+	audio_frames += frames;
+	return frames;
+}
+
+static void input_poll(void) {
+}
+
+static int16_t input_state(unsigned port, unsigned device, unsigned index, unsigned id) {
+	return port == 0 && device == RETRO_DEVICE_JOYPAD && id < 16 && (buttons & (1 << id)) != 0;
+}
+
+#define SYMBOL(var, name) \
+	if (!(*(void **)&var = dlsym(handle, name))) { \
+		error = dlerror(); \
+		return false; \
+	}
+
+bool harness_open(const char *path) {
+	if (!(handle = dlopen(path, RTLD_NOW | RTLD_LOCAL))) {
+		error = dlerror();
+		return false;
+	}
+	SYMBOL(core_init, "retro_init");
+	SYMBOL(core_deinit, "retro_deinit");
+	SYMBOL(core_set_environment, "retro_set_environment");
+	SYMBOL(core_set_video_refresh, "retro_set_video_refresh");
+	SYMBOL(core_set_audio_sample, "retro_set_audio_sample");
+	SYMBOL(core_set_audio_sample_batch, "retro_set_audio_sample_batch");
+	SYMBOL(core_set_input_poll, "retro_set_input_poll");
+	SYMBOL(core_set_input_state, "retro_set_input_state");
+	SYMBOL(core_get_system_av_info, "retro_get_system_av_info");
+	SYMBOL(core_load_game, "retro_load_game");
+	SYMBOL(core_unload_game, "retro_unload_game");
+	SYMBOL(core_run, "retro_run");
+	SYMBOL(core_serialize_size, "retro_serialize_size");
+	SYMBOL(core_serialize, "retro_serialize");
+	SYMBOL(core_unserialize, "retro_unserialize");
+
+	core_set_environment(environment);
+	core_init();
+	core_set_video_refresh(video_refresh);
+	core_set_audio_sample(audio_sample);
+	core_set_audio_sample_batch(audio_sample_batch);
+	core_set_input_poll(input_poll);
+	core_set_input_state(input_state);
+	return true;
+}
+
+const char *harness_error(void) {
+	return error;
+}
+
+void harness_close(void) {
+	core_deinit();
+}
+
+bool harness_load_game(const char *path, const void *data, size_t size) {
+	struct retro_game_info info = {path, data, size, NULL};
+	return core_load_game(&info);
+}
+
+void harness_unload_game(void) {
+	core_unload_game();
+}
+
+void harness_get_system_av_info(struct retro_system_av_info *info) {
+	core_get_system_av_info(info);
+}
+
+size_t harness_run(uint16_t pressed) {
+	buttons = pressed;
+	audio_frames = 0;
+	core_run();
+	return audio_frames;
+}
+
+const uint32_t *harness_frame(unsigned *width, unsigned *height) {
+	*width = frame_width;
+	*height = frame_height;
+	return frame;
+}
+
+int harness_num_options(void) {
+	return num_options;
+}
+
+const char *harness_option(int i, int value) {
+	return options[i][value];
+}
+
+bool harness_set_option(const char *key, const char *value) {
+	for (int i = 0; i < num_options; i++) {
+		if (strcmp(options[i][0], key) == 0) {
+			free(options[i][1]);
+			options[i][1] = strdup(value);
+			options_updated = true;
+			return true;
+		}
+	}
+	return false;
+}
+
+size_t harness_serialize_size(void) {
+	return core_serialize_size();
+}
+
+bool harness_serialize(void *data, size_t size) {
+	return core_serialize(data, size);
+}
+
+bool harness_unserialize(const void *data, size_t size) {
+	return core_unserialize(data, size);
+}
@@ -0,0 +1,168 @@
+// Package harness loads a libretro core and drives it like a frontend, to test the core.
+// A process can load a single core, which stays loaded until it exits.
+package harness
+
+/*
+#cgo LDFLAGS: -ldl
+#include <stdlib.h>
+#include "harness.h"
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// Core is the loaded core.
+type Core struct {
+	mu sync.Mutex
+}
+
+// Button ids of the RetroPad.
+const (
+	ButtonB = iota
+	ButtonY
+	ButtonSelect
+	ButtonStart
+	ButtonUp
+	ButtonDown
+	ButtonLeft
+	ButtonRight
+	ButtonA
+	ButtonX
+	ButtonL
+	ButtonR
+	ButtonL2
+	ButtonR2
+	ButtonL3
+	ButtonR3
+)
+
+var (
+	loaded *Core
+	loadMu sync.Mutex
+)
+
+// Open loads the core from a shared object and initializes it.
+func Open(path string) (*Core, error) {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	if loaded != nil {
+		return nil, errors.New("a core is already loaded")
+	}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if !C.harness_open(cpath) {
+		return nil, errors.Errorf("failed to load core: %s", C.GoString(C.harness_error()))
+	}
+	loaded = &Core{}
+	return loaded, nil
+}
+
+// Close deinitializes the core. The shared object can't be unloaded, so it can't be opened again.
+func (c *Core) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	C.harness_close()
+}
+
+// LoadGame loads a game from its content.
+func (c *Core) LoadGame(path string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	cdata := C.CBytes(data)
+	defer C.free(cdata)
+	if !C.harness_load_game(cpath, cdata, C.size_t(len(data))) {
+		return errors.Errorf("core failed to load %s", path)
+	}
+	return nil
+}
+
+// UnloadGame unloads the game.
+func (c *Core) UnloadGame() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	C.harness_unload_game()
+}
+
+// AVInfo returns the size of the screen, the frame rate and the sample rate of the audio.
+func (c *Core) AVInfo() (width, height int, fps, sampleRate float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var info C.struct_retro_system_av_info
+	C.harness_get_system_av_info(&info)
+	return int(info.geometry.base_width), int(info.geometry.base_height), float64(info.timing.fps), float64(info.timing.sample_rate)
+}
+
+// Run runs a frame with the buttons pressed, one bit per button id.
+// It returns the number of audio frames played.
+func (c *Core) Run(buttons uint16) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int(C.harness_run(C.uint16_t(buttons)))
+}
+
+// Frame returns the last frame shown, in XRGB8888.
+func (c *Core) Frame() (pixels []uint32, width, height int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var w, h C.uint
+	frame := C.harness_frame(&w, &h)
+	pixels = make([]uint32, int(w*h))
+	copy(pixels, (*[C.HARNESS_MAX_PIXELS]uint32)(unsafe.Pointer(frame))[:len(pixels)])
+	return pixels, int(w), int(h)
+}
+
+// Options returns the values of the options declared by the core.
+func (c *Core) Options() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	options := make(map[string]string)
+	for i := C.int(0); i < C.harness_num_options(); i++ {
+		options[C.GoString(C.harness_option(i, 0))] = C.GoString(C.harness_option(i, 1))
+	}
+	return options
+}
+
+// SetOption changes the value of an option, which the core sees on the next frame.
+func (c *Core) SetOption(key, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ckey, cvalue := C.CString(key), C.CString(value)
+	defer C.free(unsafe.Pointer(ckey))
+	defer C.free(unsafe.Pointer(cvalue))
+	if !C.harness_set_option(ckey, cvalue) {
+		return errors.Errorf("unknown option %q", key)
+	}
+	return nil
+}
+
+// Serialize returns a save state.
+func (c *Core) Serialize() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size := C.harness_serialize_size()
+	data := C.malloc(size)
+	defer C.free(data)
+	if !C.harness_serialize(data, size) {
+		return nil, errors.New("core failed to serialize")
+	}
+	return C.GoBytes(data, C.int(size)), nil
+}
+
+// Unserialize restores a save state.
+func (c *Core) Unserialize(state []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data := C.CBytes(state)
+	defer C.free(data)
+	if !C.harness_unserialize(data, C.size_t(len(state))) {
+		return errors.New("core failed to unserialize")
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+#include "../libretro.h"
+
+#define HARNESS_MAX_PIXELS (64 * 32)
+#define HARNESS_MAX_OPTIONS 64
+
+bool harness_open(const char *path);
+const char *harness_error(void);
+void harness_close(void);
+bool harness_load_game(const char *path, const void *data, size_t size);
+void harness_unload_game(void);
+void harness_get_system_av_info(struct retro_system_av_info *info);
+size_t harness_run(uint16_t pressed);
+const uint32_t *harness_frame(unsigned *width, unsigned *height);
+int harness_num_options(void);
+const char *harness_option(int i, int value);
+bool harness_set_option(const char *key, const char *value);
+size_t harness_serialize_size(void);
+bool harness_serialize(void *data, size_t size);
+bool harness_unserialize(const void *data, size_t size);
@@ -0,0 +1,76 @@
+package harness_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/gemulation/chip8/libretro/harness"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the core")
+	}
+	dir, err := ioutil.TempDir("", "chip8_libretro")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	so := filepath.Join(dir, "chip8_libretro.so")
+	out, err := exec.Command("go", "build", "-buildmode=c-shared", "-o", so, "github.com/gemulation/chip8/libretro").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	core, err := harness.Open(so)
+	require.NoError(t, err)
+	defer core.Close()
+
+	options := core.Options()
+	require.Equal(t, "12", options["chip8_speed"])
+	require.Equal(t, "green", options["chip8_palette"])
+	require.Equal(t, "disabled", options["chip8_quirk_clip"])
+
+	// without a game, the core does nothing
+	require.Equal(t, 0, core.Run(0))
+	_, err = core.Serialize()
+	require.Error(t, err)
+	require.Error(t, core.Unserialize([]byte{1}))
+
+	rom, err := ioutil.ReadFile("../../roms/brix.rom")
+	require.NoError(t, err)
+	require.NoError(t, core.LoadGame("brix.rom", rom))
+	defer core.UnloadGame()
+
+	width, height, fps, sampleRate := core.AVInfo()
+	require.Equal(t, 64, width)
+	require.Equal(t, 32, height)
+	require.Equal(t, 60.0, fps)
+	require.Equal(t, 735, core.Run(0))
+	require.Equal(t, int(sampleRate/fps), 735)
+
+	for i := 0; i < 60; i++ {
+		core.Run(1 << harness.ButtonLeft)
+	}
+	pixels, width, height := core.Frame()
+	require.Equal(t, 64, width)
+	require.Equal(t, 32, height)
+	require.Contains(t, pixels, uint32(0xADFF2F))
+	require.Contains(t, pixels, uint32(0x000000))
+
+	require.NoError(t, core.SetOption("chip8_palette", "white"))
+	core.Run(0)
+	pixels, _, _ = core.Frame()
+	require.Contains(t, pixels, uint32(0xFFFFFF))
+	require.NotContains(t, pixels, uint32(0xADFF2F))
+
+	state, err := core.Serialize()
+	require.NoError(t, err)
+	for i := 0; i < 30; i++ {
+		core.Run(1 << harness.ButtonRight)
+	}
+	require.NoError(t, core.Unserialize(state))
+	restored, err := core.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, state, restored)
+}
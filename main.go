@@ -1,17 +1,142 @@
+// Command chip8 runs CHIP-8 ROMs, and disassembles, assembles, inspects, benchmarks and checks them.
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/gemulation/chip8/chip8"
+	"github.com/pkg/errors"
 )
 
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1 // the command failed
+	exitUsage   = 2 // the command line is invalid
+	exitFault   = 3 // the program run faulted
+)
+
+// command is a subcommand of the command line.
+type command struct {
+	name    string
+	args    string // arguments after the flags, for the usage
+	summary string
+	flags   func(flags *flag.FlagSet) // defines the flags, parsed before run
+	run     func(flags *flag.FlagSet, args []string) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		runCommand,
+		{name: "disasm", args: "ROM", summary: "disassemble a ROM", flags: disasmFlags, run: disasm},
+		{name: "asm", args: "SOURCE", summary: "assemble a program into a ROM", flags: asmFlags, run: asm},
+		{name: "info", args: "ROM...", summary: "show the size, hash, platform and metadata of ROMs", flags: infoFlags, run: info},
+		{name: "bench", args: "ROM", summary: "measure the instructions emulated per second, without display", flags: benchFlags, run: bench},
+		{name: "test", args: "[GOLDEN]", summary: "run the ROMs of a golden file and check their screens", flags: testFlags, run: test},
+	}
+}
+
+// usageError is an invalid command line.
+type usageError struct {
+	error
+}
+
 func main() {
-	rom, err := chip8.NewROM(os.Args[1])
-	if err != nil {
-		panic(err)
+	os.Exit(execute(os.Args[1:], os.Stderr))
+}
+
+// execute runs the command named by the first argument, run when none is, and returns the exit code.
+func execute(args []string, stderr io.Writer) int {
+	cmd := runCommand
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			if len(args) > 1 && lookup(args[1]) != nil {
+				newFlagSet(lookup(args[1]), stderr).Usage()
+			} else {
+				usage(stderr)
+			}
+			return exitOK
+		}
+		if c := lookup(args[0]); c != nil {
+			cmd, args = c, args[1:]
+		}
+	}
+
+	flags := newFlagSet(cmd, stderr)
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage // the flag package printed the error and the usage
 	}
+	err := cmd.run(flags, flags.Args())
+	switch err.(type) {
+	case nil:
+		return exitOK
+	case usageError:
+		fmt.Fprintf(stderr, "chip8 %s: %v\n", cmd.name, err)
+		flags.Usage()
+		return exitUsage
+	case *chip8.Fault:
+		fmt.Fprintf(stderr, "chip8 %s: %v\n", cmd.name, err)
+		return exitFault
+	}
+	fmt.Fprintf(stderr, "chip8 %s: %v\n", cmd.name, err)
+	return exitFailure
+}
+
+func lookup(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// newFlagSet returns the flags of a command, with its usage.
+func newFlagSet(cmd *command, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: chip8 %s [flags] %s\n\n%s.\n", cmd.name, cmd.args, capitalize(cmd.summary))
+		if cmd == runCommand {
+			fmt.Fprint(stderr, "Without a ROM, or with a directory of ROMs, the ROM is chosen in the launcher.\n")
+		}
+		fmt.Fprint(stderr, "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	cmd.flags(flags)
+	return flags
+}
+
+// usage prints the commands.
+func usage(w io.Writer) {
+	fmt.Fprint(w, "Usage: chip8 [COMMAND] [flags] [arguments]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprint(w, "\nWithout a command, the arguments are those of run.\n"+
+		"Run \"chip8 help COMMAND\" for the flags of a command.\n"+
+		"The exit code is 0 on success, 1 when the command fails, 2 when the command line is invalid\n"+
+		"and 3 when the program run faults.\n")
+}
 
-	emulator := chip8.NewEmulator(rom)
-	emulator.Run()
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// oneArg returns the single argument of a command.
+func oneArg(args []string, name string) (string, error) {
+	if len(args) != 1 {
+		return "", usageError{errors.Errorf("expected one %s", name)}
+	}
+	return args[0], nil
 }
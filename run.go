@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/gemulation/chip8/chip8/window"
+	"github.com/pkg/errors"
+)
+
+var runCommand = &command{name: "run", args: "[ROM|DIRECTORY]", summary: "run a ROM", flags: runFlags, run: run}
+
+// runOptions are the flags of run.
+var runOptions struct {
+	theme, pixels, filter, quirks string
+	speed, scale                  int
+	seed                          int64
+	capture                       chip8.Capture
+	keymap, keymapConfig          string
+	library                       string
+	keypad, hud, debug            bool
+	headless                      bool
+	frames                        int
+	input, dump, dumpFile, halt   string
+	terminal                      string
+	keyTimeout                    time.Duration
+	browser, vnc                  string
+	vncScale                      int
+}
+
+func runFlags(flags *flag.FlagSet) {
+	o := &runOptions
+	flags.StringVar(&o.theme, "theme", chip8.Themes[0].Name, "theme of the display (green, amber, white, octo, contrast) or a \"background,foreground\" hex palette")
+	flags.StringVar(&o.theme, "palette", chip8.Themes[0].Name, "same as -theme")
+	flags.StringVar(&o.pixels, "pixels", chip8.SquarePixels.String(), "style of the pixels (square, rounded, dotted, grid)")
+	flags.StringVar(&o.filter, "filter", "none", "flicker reduction filter (none, or, blend[:frames], phosphor[:decay])")
+	flags.IntVar(&o.speed, "speed", chip8.InstructionsPerFrame, "instructions executed per frame, at 60 frames per second")
+	flags.StringVar(&o.quirks, "quirks", "none", "behaviors of other interpreters (none, vip, schip, or a list of shift, loadstore, jump, clip, vfreset)")
+	flags.IntVar(&o.scale, "scale", chip8.DisplayScaleFactor, "initial size of the pixels in the window")
+	flags.Int64Var(&o.seed, "seed", 0, "seed of the random numbers, to replay a run (random when not given)")
+	flags.IntVar(&o.capture.Scale, "capture-scale", chip8.DefaultCaptureScale, "size of the pixels in screenshots and recordings")
+	flags.StringVar(&o.capture.Screenshot, "screenshot", "", "save the screen into this PNG file")
+	flags.IntVar(&o.capture.ScreenshotFrame, "screenshot-frame", 0, "frame of the screenshot, 0 for when the emulator stops")
+	flags.StringVar(&o.capture.GIF, "gif", "", "record the screen into this animated GIF file")
+	flags.IntVar(&o.capture.GIFFrom, "gif-from", 1, "first frame of the recording")
+	flags.IntVar(&o.capture.GIFFrames, "gif-frames", 0, "number of frames to record, 0 for until the emulator stops")
+	flags.StringVar(&o.keymap, "keymap", "", "keys of the keypad (qwerty, azerty, dvorak, numpad) or the 16 keys from 0 to F separated by spaces, instead of the keymap of the ROM")
+	flags.StringVar(&o.keymapConfig, "keymap-config", chip8.DefaultKeymapConfig(), "file of the keymaps of the ROMs, where the keys rebound with F4 are saved")
+	flags.StringVar(&o.library, "library", chip8.DefaultLibrary(), "file of the recent ROMs and of the settings of the ROMs")
+	flags.BoolVar(&o.keypad, "keypad", false, "show the keypad beside the screen, its keys can be clicked (toggled with F5)")
+	flags.BoolVar(&o.hud, "hud", false, "show the rates of the emulation and the registers over the screen (toggled with F10)")
+	flags.BoolVar(&o.debug, "debug", false, "open the debugger in another window (toggled with F1)")
+	flags.BoolVar(&o.headless, "headless", false, "run without display, with the keys of -input, as fast as possible")
+	flags.IntVar(&o.frames, "frames", 0, "frames run headless, 0 for until the program ends")
+	flags.StringVar(&o.input, "input", "", "keys pressed headless, such as \"10:5 60:A+ 90:A-\" to tap 5 at frame 10 and hold A from frame 60 to 90, or @FILE")
+	flags.StringVar(&o.halt, "halt", "none", "ends of the program stopping headless (none, all, or a list of selfjump, loop, keywait)")
+	flags.StringVar(&o.dump, "dump", "", "write the screen when headless stops (ascii, png, hash)")
+	flags.StringVar(&o.dumpFile, "dump-file", "", "file of the dump instead of the standard output")
+	flags.StringVar(&o.terminal, "terminal", "", "run in the terminal instead of a window (halfblock, braille, sixel)")
+	flags.DurationVar(&o.keyTimeout, "key-timeout", chip8.DefaultKeyTimeout, "how long a key typed in the terminal is held")
+	flags.StringVar(&o.browser, "browser", "", "serve the screen to browsers on this address, such as "+chip8.DefaultBrowserAddr)
+	flags.StringVar(&o.vnc, "vnc", "", "serve the screen to VNC viewers on this address, such as "+chip8.DefaultVNCAddr)
+	flags.IntVar(&o.vncScale, "vnc-scale", chip8.DefaultVNCScale, "size of the pixels served to VNC viewers")
+}
+
+func run(flags *flag.FlagSet, args []string) (err error) {
+	o := &runOptions
+	if len(args) > 1 {
+		return usageError{errors.New("expected a single ROM or directory")}
+	}
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	library, err := chip8.LoadLibrary(o.library)
+	if err != nil {
+		return err
+	}
+	theme, err := chip8.ParseTheme(o.theme)
+	if err != nil {
+		return err
+	}
+	// without a ROM, or with a directory, the ROM is chosen in the launcher
+	var filename string
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	if info, err := os.Stat(filename); filename == "" || err == nil && info.IsDir() {
+		if o.headless || set["frames"] || o.input != "" || o.dump != "" || set["halt"] {
+			return usageError{errors.New("-headless, -frames, -input, -halt and -dump need a ROM file")}
+		}
+		dir := filename
+		if dir == "" {
+			dir = "roms"
+		}
+		if filename, err = window.Launch(dir, library, theme); err != nil || filename == "" {
+			return err
+		}
+	}
+	rom, err := chip8.NewROM(filename)
+	if err != nil {
+		return err
+	}
+	filter, err := chip8.ParseFilter(o.filter)
+	if err != nil {
+		return err
+	}
+
+	// the settings given override those of the ROM, which are remembered when the emulator stops
+	emulator := chip8.NewEmulator(rom)
+	settings := library.Settings(rom)
+	if set["theme"] || set["palette"] || settings.Theme == "" {
+		settings.Theme = o.theme
+	}
+	if set["pixels"] || settings.Pixels == "" {
+		settings.Pixels = o.pixels
+	}
+	if set["speed"] {
+		settings.Speed = o.speed
+	}
+	if set["quirks"] {
+		settings.Quirks = o.quirks
+	}
+	if err := settings.Apply(emulator); err != nil {
+		return err
+	}
+	if set["seed"] {
+		emulator.SetSeed(o.seed)
+	}
+	emulator.SetFilter(filter)
+	emulator.SetCapture(o.capture)
+	if err := emulator.LoadKeymaps(o.keymapConfig); err != nil {
+		return err
+	}
+	if o.keymap != "" {
+		keymap, err := chip8.ParseKeymap(o.keymap)
+		if err != nil {
+			return err
+		}
+		emulator.SetKeymap(keymap)
+	}
+
+	if o.headless {
+		return runHeadless(emulator, theme)
+	}
+	if o.input != "" || o.dump != "" || set["halt"] {
+		return usageError{errors.New("-input, -halt and -dump need -headless")}
+	}
+	// the ROMs played are remembered, not those run headless by scripts
+	defer func() {
+		library.AddRecent(filename)
+		library.SetSettings(rom, emulator.Settings())
+		if saveErr := library.Save(o.library); err == nil {
+			err = saveErr
+		}
+	}()
+	if speaker, err := chip8.NewSpeakerSink(chip8.NewTone(chip8.DefaultPitch, chip8.DefaultVolume)); err == nil {
+		emulator.SetAudioSink(speaker)
+	}
+	switch {
+	case o.vnc != "":
+		vnc := chip8.NewVNC(o.vnc)
+		vnc.Scale = o.vncScale
+		return emulator.RunFrontend(vnc)
+	case o.browser != "":
+		return emulator.RunFrontend(chip8.NewBrowser(o.browser))
+	case o.terminal != "":
+		mode, err := chip8.ParseTerminalMode(o.terminal)
+		if err != nil {
+			return err
+		}
+		terminal := chip8.NewTerminal(mode)
+		terminal.KeyTimeout = o.keyTimeout
+		emulator.SetTrace(nil)
+		if err := emulator.RunFrontend(terminal); err != nil {
+			return err
+		}
+		return emulator.Fault()
+	}
+	w := window.New()
+	w.SetScale(o.scale)
+	w.Panel = o.keypad
+	w.HUD = o.hud
+	w.Debug = o.debug
+	w.Run(emulator)
+	return emulator.Fault()
+}
+
+// runHeadless runs the emulator without display for the frames given or until the program stops,
+// then dumps the screen. The fault of the program is returned, for the exit code.
+func runHeadless(emulator *chip8.Emulator, theme chip8.Theme) error {
+	o := &runOptions
+	headless := chip8.NewHeadless(o.frames)
+	if o.input != "" {
+		input := o.input
+		if strings.HasPrefix(input, "@") {
+			data, err := ioutil.ReadFile(input[1:])
+			if err != nil {
+				return errors.Wrap(err, "failed to read input")
+			}
+			input = string(data)
+		}
+		events, err := chip8.ParseInput(input)
+		if err != nil {
+			return err
+		}
+		headless.Input = events
+	}
+	halts, err := chip8.ParseHaltDetectors(o.halt)
+	if err != nil {
+		return err
+	}
+	headless.Halts = halts
+	switch o.dump {
+	case "", "ascii", "png", "hash":
+	default:
+		return usageError{errors.Errorf("unknown dump %q, expected ascii, png or hash", o.dump)}
+	}
+
+	emulator.SetTrace(nil)
+	if err := emulator.RunFrontend(headless); err != nil {
+		return err
+	}
+	if halt := emulator.Halt(); halt != nil {
+		fmt.Fprintf(os.Stderr, "halted after %d frames: %s\n", emulator.State().Frame, halt)
+	}
+	screen := emulator.Screen()
+	switch o.dump {
+	case "ascii":
+		err = output(o.dumpFile, []byte(screen.ASCII()))
+	case "png":
+		var out bytes.Buffer
+		if err = png.Encode(&out, screen.Paletted(theme, o.capture.Scale)); err == nil {
+			err = output(o.dumpFile, out.Bytes())
+		}
+	case "hash":
+		err = output(o.dumpFile, []byte(screen.Hash()+"\n"))
+	}
+	if err != nil {
+		return err
+	}
+	return emulator.Fault()
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/pkg/errors"
+)
+
+var disasmOptions struct {
+	output string
+}
+
+func disasmFlags(flags *flag.FlagSet) {
+	flags.StringVar(&disasmOptions.output, "o", "", "file of the disassembly instead of the standard output")
+}
+
+func disasm(flags *flag.FlagSet, args []string) error {
+	filename, err := oneArg(args, "ROM")
+	if err != nil {
+		return err
+	}
+	rom, err := chip8.NewROM(filename)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "; %s, %d bytes\n", rom.Name, len(rom.Data))
+	if err := chip8.WriteDisassembly(&out, rom.Data); err != nil {
+		return err
+	}
+	return output(disasmOptions.output, out.Bytes())
+}
+
+var asmOptions struct {
+	output string
+}
+
+func asmFlags(flags *flag.FlagSet) {
+	flags.StringVar(&asmOptions.output, "o", "", "file of the ROM, the source with the extension .ch8 by default")
+}
+
+func asm(flags *flag.FlagSet, args []string) error {
+	filename, err := oneArg(args, "source")
+	if err != nil {
+		return err
+	}
+	source, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to read source")
+	}
+	defer source.Close()
+	rom, err := chip8.Assemble(source)
+	if err != nil {
+		return errors.Wrap(err, filename)
+	}
+	out := asmOptions.output
+	if out == "" {
+		out = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".ch8"
+	}
+	return output(out, rom)
+}
+
+// output writes into a file, or the standard output.
+func output(filename string, data []byte) error {
+	if filename == "" || filename == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return errors.Wrap(ioutil.WriteFile(filename, data, 0644), "failed to write output")
+}
+
+var infoOptions struct {
+	library, keymapConfig string
+}
+
+func infoFlags(flags *flag.FlagSet) {
+	flags.StringVar(&infoOptions.library, "library", chip8.DefaultLibrary(), "file of the settings of the ROMs")
+	flags.StringVar(&infoOptions.keymapConfig, "keymap-config", chip8.DefaultKeymapConfig(), "file of the keymaps of the ROMs")
+}
+
+func info(flags *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		return usageError{errors.New("expected ROMs")}
+	}
+	library, err := chip8.LoadLibrary(infoOptions.library)
+	if err != nil {
+		return err
+	}
+	keymaps, err := chip8.LoadKeymapConfig(infoOptions.keymapConfig)
+	if err != nil {
+		return err
+	}
+	for i, filename := range args {
+		rom, err := chip8.NewROM(filename)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		instructions := 0
+		for addr := 0; addr+1 < len(rom.Data); addr += chip8.InstructionSize {
+			if !strings.HasPrefix(chip8.Disassemble(uint16(rom.Data[addr])<<8|uint16(rom.Data[addr+1])), "DW") {
+				instructions++
+			}
+		}
+		fmt.Printf("name:         %s\n", rom.Name)
+		fmt.Printf("size:         %d bytes\n", len(rom.Data))
+		fmt.Printf("sha1:         %s\n", rom.Hash())
+		fmt.Printf("platform:     %s\n", chip8.DetectPlatform(rom.Data))
+		fmt.Printf("instructions: %d of %d words\n", instructions, len(rom.Data)/chip8.InstructionSize)
+		for _, s := range texts(rom.Data, 6) {
+			fmt.Printf("text:         %q\n", s)
+		}
+		settings := library.Settings(rom)
+		if settings != (chip8.Settings{}) {
+			fmt.Printf("settings:     theme %s, pixels %s, speed %d, quirks %s\n", settings.Theme, settings.Pixels, settings.Speed, settings.Quirks)
+		}
+		if keymap, err := keymaps.Keymap(rom); err == nil {
+			fmt.Printf("keymap:       %s (%s)\n", keymap.Name, keymap.Spec())
+		}
+	}
+	return nil
+}
+
+// texts returns the runs of printable characters of a ROM, such as its title or author.
+func texts(data []byte, min int) []string {
+	var texts []string
+	start := 0
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] >= ' ' && data[i] < 0x7F {
+			continue
+		}
+		if s := strings.TrimSpace(string(data[start:i])); len(s) >= min && strings.ContainsAny(s, "abcdefghijklmnopqrstuvwxyz") {
+			texts = append(texts, s)
+		}
+		start = i + 1
+	}
+	return texts
+}
+
+var benchOptions struct {
+	duration time.Duration
+	speed    int
+	quirks   string
+}
+
+func benchFlags(flags *flag.FlagSet) {
+	flags.DurationVar(&benchOptions.duration, "duration", 2*time.Second, "how long to run the ROM")
+	flags.IntVar(&benchOptions.speed, "speed", chip8.MaxSpeed, "instructions executed per frame")
+	flags.StringVar(&benchOptions.quirks, "quirks", "none", "behaviors of other interpreters (none, vip, schip, or a list)")
+}
+
+func bench(flags *flag.FlagSet, args []string) error {
+	filename, err := oneArg(args, "ROM")
+	if err != nil {
+		return err
+	}
+	rom, err := chip8.NewROM(filename)
+	if err != nil {
+		return err
+	}
+	quirks, err := chip8.ParseQuirks(benchOptions.quirks)
+	if err != nil {
+		return err
+	}
+	emulator := chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.SetSeed(1)
+	emulator.SetSpeed(benchOptions.speed)
+	emulator.SetQuirks(quirks)
+	emulator.Reset()
+
+	frames := 0
+	start := time.Now()
+	for time.Since(start) < benchOptions.duration && emulator.Frame() {
+		frames++
+	}
+	elapsed := time.Since(start).Seconds()
+	fmt.Printf("%s: %d frames, %d instructions in %.2fs: %.0f instructions/s, %.0f frames/s\n",
+		rom.Name, frames, emulator.Instructions(), elapsed, float64(emulator.Instructions())/elapsed, float64(frames)/elapsed)
+	return nil
+}
+
+// goldenSeed seeds the random numbers of the golden checks, so that their screens are always the same.
+const goldenSeed = 1
+
+var testOptions struct {
+	update bool
+}
+
+func testFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&testOptions.update, "update", false, "write the screens into the golden file instead of checking them")
+}
+
+// test runs the checks of a golden file, roms/golden.txt by default. Its lines are
+//
+//	ROM FRAMES SCREEN
+//
+// with the path of a ROM relative to the file, the frames it runs and the SHA-1 of its screen then,
+// and the lines starting with # are comments. A program faulting fails its check, and the first fault is returned.
+func test(flags *flag.FlagSet, args []string) error {
+	filename := filepath.Join("roms", "golden.txt")
+	if len(args) > 1 {
+		return usageError{errors.New("expected one golden file")}
+	}
+	if len(args) == 1 {
+		filename = args[0]
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to read golden file")
+	}
+
+	var updated bytes.Buffer
+	checks, failed := 0, 0
+	var fault error // first fault of the programs
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			fmt.Fprintln(&updated, scanner.Text())
+			continue
+		}
+		if len(fields) != 3 {
+			return errors.Errorf("%s:%d: expected a ROM, frames and a screen", filename, line)
+		}
+		frames, err := strconv.Atoi(fields[1])
+		if err != nil || frames <= 0 {
+			return errors.Errorf("%s:%d: invalid frames %q", filename, line, fields[1])
+		}
+		rom, err := chip8.NewROM(filepath.Join(filepath.Dir(filename), fields[0]))
+		if err != nil {
+			return errors.Wrapf(err, "%s:%d", filename, line)
+		}
+		emulator := chip8.NewEmulator(rom)
+		emulator.SetTrace(nil)
+		emulator.SetSeed(goldenSeed)
+		if err := emulator.RunFrontend(chip8.NewHeadless(frames)); err != nil {
+			return err
+		}
+		screen := emulator.Screen()
+		hash := screen.Hash()
+		err = emulator.Fault()
+		if err != nil {
+			fmt.Fprintln(&updated, scanner.Text()) // the screen of a fault isn't the expected one
+		} else {
+			fmt.Fprintln(&updated, fields[0], frames, hash)
+		}
+
+		checks++
+		switch {
+		case err != nil:
+			failed++
+			if fault == nil {
+				fault = err
+			}
+			fmt.Printf("FAULT   %s: %v\n", fields[0], err)
+		case testOptions.update:
+			fmt.Printf("updated %s\n", fields[0])
+		case hash != fields[2]:
+			failed++
+			fmt.Printf("FAIL    %s: screen %.8s instead of %.8s after %d frames\n", fields[0], hash, fields[2], frames)
+		default:
+			fmt.Printf("ok      %s\n", fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read golden file")
+	}
+
+	if testOptions.update {
+		if err := ioutil.WriteFile(filename, updated.Bytes(), 0644); err != nil {
+			return errors.Wrap(err, "failed to update golden file")
+		}
+	}
+	if fault != nil {
+		return fault
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d checks failed", failed, checks)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Command wasm runs the emulator in a browser. It is built with
+//
+//	GOOS=js GOARCH=wasm go build -o wasm/chip8.wasm ./wasm
+//
+// and exposes a global chip8 object to the page:
+//
+//	chip8.load(name, bytes)    loads a ROM from a Uint8Array and resets the machine, returns an Error when it is too large
+//	chip8.frames(n)            runs n frames, returns false when the program ended
+//	chip8.screen()             returns the 64x32 pixels as a Uint8Array, 1 when on
+//	chip8.setKey(key, pressed) presses or releases a key of the keypad, from 0 to 15
+//	chip8.buzzing()            tells if the buzzer is on
+//	chip8.saveState()          returns a snapshot as a Uint8Array
+//	chip8.loadState(bytes)     restores a snapshot
+package main
+
+import (
+	"bytes"
+	"syscall/js"
+
+	"github.com/gemulation/chip8/chip8"
+	"github.com/pkg/errors"
+)
+
+var emulator *chip8.Emulator
+
+func main() {
+	js.Global().Set("chip8", js.ValueOf(map[string]interface{}{
+		"load":      js.FuncOf(load),
+		"frames":    js.FuncOf(frames),
+		"screen":    js.FuncOf(screen),
+		"setKey":    js.FuncOf(setKey),
+		"buzzing":   js.FuncOf(buzzing),
+		"saveState": js.FuncOf(saveState),
+		"loadState": js.FuncOf(loadState),
+	}))
+	select {}
+}
+
+func load(this js.Value, args []js.Value) interface{} {
+	rom := &chip8.ROM{Name: args[0].String(), Data: bytesOf(args[1])}
+	if err := rom.Check(); err != nil {
+		return jsError(err)
+	}
+	emulator = chip8.NewEmulator(rom)
+	emulator.SetTrace(nil)
+	emulator.Reset()
+	return nil
+}
+
+func frames(this js.Value, args []js.Value) interface{} {
+	if emulator == nil {
+		return false
+	}
+	for n := args[0].Int(); n > 0; n-- {
+		if !emulator.Frame() {
+			return false
+		}
+	}
+	return true
+}
+
+func screen(this js.Value, args []js.Value) interface{} {
+	var pixels chip8.Screen
+	if emulator != nil {
+		pixels = emulator.Screen()
+	}
+	return uint8Array(pixels[:])
+}
+
+func setKey(this js.Value, args []js.Value) interface{} {
+	if emulator != nil {
+		emulator.SetKey(args[0].Int(), args[1].Truthy())
+	}
+	return nil
+}
+
+func buzzing(this js.Value, args []js.Value) interface{} {
+	return emulator != nil && emulator.State().ST > 0
+}
+
+func saveState(this js.Value, args []js.Value) interface{} {
+	if emulator == nil {
+		return nil
+	}
+	var state bytes.Buffer
+	if err := emulator.SaveState(&state); err != nil {
+		return jsError(err)
+	}
+	return uint8Array(state.Bytes())
+}
+
+func loadState(this js.Value, args []js.Value) interface{} {
+	if emulator == nil {
+		return jsError(errors.New("no ROM loaded"))
+	}
+	if err := emulator.LoadState(bytes.NewReader(bytesOf(args[0]))); err != nil {
+		return jsError(err)
+	}
+	return nil
+}
+
+func bytesOf(array js.Value) []byte {
+	b := make([]byte, array.Get("length").Int())
+	js.CopyBytesToGo(b, array)
+	return b
+}
+
+func uint8Array(b []byte) js.Value {
+	array := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(array, b)
+	return array
+}
+
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}